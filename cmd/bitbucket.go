@@ -0,0 +1,46 @@
+// Package cmd is the entry point for cobra cli
+package cmd
+
+import (
+	"fuse/internal/providers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bitbucketURL      string
+	bitbucketProject  string
+	bitbucketUsername string
+)
+
+var bitbucketCmd = &cobra.Command{
+	Use:   "bitbucket",
+	Short: "Fuse for Bitbucket Server and Bitbucket Cloud",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun(cmd, "pat", "project")
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		return fuseRepositories(func(repository string) providers.Provider {
+			return &providers.Bitbucket{
+				Common:      commonInput(repository, bitbucketProject),
+				PullRequest: pullRequestInput(),
+				BaseURL:     bitbucketURL,
+				Project:     bitbucketProject,
+				Username:    bitbucketUsername,
+			}
+		})
+	},
+}
+
+func init() {
+	bitbucketCmd.Flags().StringVarP(&bitbucketURL, "baseUrl", "u", providers.BitbucketCloudURL,
+		"Bitbucket Server base url. Defaults to Bitbucket Cloud.")
+	bitbucketCmd.Flags().StringVarP(&bitbucketProject, "project", "p", "",
+		"Bitbucket Server project key or Bitbucket Cloud workspace.")
+	bitbucketCmd.Flags().StringVarP(&bitbucketUsername, "username", "n", "",
+		"Username to authenticate with the pat (e.g. Bitbucket Cloud app passwords). If omitted the pat is used as a bearer token.")
+
+	rootCmd.AddCommand(bitbucketCmd)
+}
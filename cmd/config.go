@@ -0,0 +1,108 @@
+// Package cmd is the entry point for cobra cli
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// flags holding paths that, when set in the config file, are relative to the config file directory
+var configRelativePaths = map[string]bool{
+	"contentDir": true,
+	"reposFile":  true,
+}
+
+// readConfig reads the config file, if any, and FUSE_* environment variables, e.g: FUSE_PAT.
+// Config keys are named after the cli flags, plus provider to choose the provider when running fuse without a sub command.
+func readConfig() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetEnvPrefix("fuse")
+	v.AutomaticEnv()
+
+	if configFile == "" {
+		return v, nil
+	}
+
+	v.SetConfigFile(configFile)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrap(err, "Unable to read config file")
+	}
+
+	return v, nil
+}
+
+// loadConfig sets every flag of cmd that was not explicitly provided through the command line from the config.
+// Command line flags always take precedence over the config file.
+func loadConfig(cmd *cobra.Command) error {
+	v, err := readConfig()
+
+	if err != nil {
+		return err
+	}
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || !v.IsSet(flag.Name) {
+			return
+		}
+
+		values, isList := v.Get(flag.Name).([]interface{})
+
+		if !isList {
+			values = []interface{}{v.Get(flag.Name)}
+		}
+
+		for _, value := range values {
+			strValue := fmt.Sprint(value)
+
+			if configRelativePaths[flag.Name] && v.InConfig(strings.ToLower(flag.Name)) && !filepath.IsAbs(strValue) {
+				strValue = filepath.Join(filepath.Dir(configFile), strValue)
+			}
+
+			if err = cmd.Flags().Set(flag.Name, strValue); err != nil {
+				err = errors.Wrap(err, "Invalid config value for "+flag.Name)
+				return
+			}
+		}
+	})
+
+	return err
+}
+
+// runConfiguredProvider runs the provider command named by the provider key of the config file
+func runConfiguredProvider(cmd *cobra.Command, args []string) error {
+	v, err := readConfig()
+
+	if err != nil {
+		return err
+	}
+
+	provider := v.GetString("provider")
+
+	if provider == "" {
+		return cmd.Help()
+	}
+
+	providerCmd, _, err := cmd.Find([]string{provider})
+
+	if err != nil || providerCmd == cmd {
+		return errors.New("unknown provider " + provider)
+	}
+
+	// makes the root flags, already parsed, available to the provider command
+	if err = providerCmd.ParseFlags(nil); err != nil {
+		return err
+	}
+
+	if err = providerCmd.PreRunE(providerCmd, args); err != nil {
+		return err
+	}
+
+	return providerCmd.RunE(providerCmd, args)
+}
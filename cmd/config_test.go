@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuse-config")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	configFile = filepath.Join(dir, "fuse.yaml")
+	defer func() { configFile = "" }()
+
+	err = ioutil.WriteFile(configFile, []byte("repoName: [a, b]\ncontentDir: content\ntag: v1\nprEnabled: true\n"), os.ModePerm)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		repositories []string
+		content, tag string
+		enabled      bool
+	)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringSliceVar(&repositories, "repoName", nil, "")
+	cmd.Flags().StringVar(&content, "contentDir", "", "")
+	cmd.Flags().StringVar(&tag, "tag", "latest", "")
+	cmd.Flags().BoolVar(&enabled, "prEnabled", false, "")
+
+	if err = cmd.Flags().Parse([]string{"--tag", "v2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = loadConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repositories) != 2 || repositories[0] != "a" || repositories[1] != "b" {
+		t.Errorf("unexpected repositories %v", repositories)
+	}
+
+	if content != filepath.Join(dir, "content") {
+		t.Errorf("expected content dir relative to the config file, got %s", content)
+	}
+
+	if tag != "v2" || !enabled {
+		t.Errorf("expected command line flags to take precedence, got tag %s and pr enabled %v", tag, enabled)
+	}
+}
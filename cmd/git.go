@@ -0,0 +1,41 @@
+// Package cmd is the entry point for cobra cli
+package cmd
+
+import (
+	"strings"
+
+	"fuse/internal/providers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteURL string
+)
+
+var gitCmd = &cobra.Command{
+	Use:   "git",
+	Short: "Fuse for any git remote, without pull requests",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun(cmd, "url")
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		return fuseRepositories(func(repository string) providers.Provider {
+			return &providers.GitRemote{
+				Common:      commonInput(repository, ""),
+				PullRequest: pullRequestInput(),
+				URL:         strings.Replace(remoteURL, "{repoName}", repository, -1),
+			}
+		})
+	},
+}
+
+func init() {
+	gitCmd.Flags().StringVarP(&remoteURL, "url", "u", "",
+		`Git remote url, e.g. https://git.mycompany.io/repository.git or file:///srv/git/repository.git.
+				{repoName} is replaced by each target repository name, e.g. file:///srv/git/{repoName}.git`)
+
+	rootCmd.AddCommand(gitCmd)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"fuse/internal/providers"
+	"fuse/internal/workflow"
+)
+
+// runCaptured runs the root command with the given arguments, returning what it wrote to stdout
+func runCaptured(t *testing.T, args ...string) []byte {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	// the outputs were bound to stdout before it was replaced
+	workflow.Output, providers.Output = writer, writer
+
+	defer func() {
+		os.Stdout = stdout
+		workflow.Output, providers.Output = os.Stdout, os.Stdout
+	}()
+
+	output := make(chan []byte)
+
+	go func() {
+		content, _ := ioutil.ReadAll(reader)
+		output <- content
+	}()
+
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	writer.Close()
+	content := <-output
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+func TestGitReportToStdout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	root, err := ioutil.TempDir("", "fuse-cmd")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	seed, content := filepath.Join(root, "seed"), filepath.Join(root, "content")
+
+	for _, dir := range []string{seed, content} {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(seed, "config.yaml"), []byte("a: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(content, "config.yaml"), []byte("a: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q", seed},
+		{"-C", seed, "checkout", "-q", "-b", "master"},
+		{"-C", seed, "add", "-A"},
+		{"-C", seed, "-c", "user.name=seed", "-c", "user.email=seed@fuse.io", "commit", "-q", "-m", "seed"},
+		{"clone", "-q", "--bare", seed, filepath.Join(root, "remote.git")},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	common := []string{"git", "--url", "file://" + filepath.Join(root, "remote.git"), "--repoName", "remote",
+		"--contentDir", content, "--report", "-", "--pretty=false"}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected workflow.Status
+	}{
+		{"dry run", []string{"--dryRun"}, workflow.StatusDryRun},
+		{"pull request", []string{"--dryRun=false", "--prEnabled"}, workflow.StatusPushed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout := runCaptured(t, append(append([]string{}, common...), test.args...)...)
+
+			var report struct {
+				Repositories []struct {
+					Status workflow.Status `json:"status"`
+				} `json:"repositories"`
+			}
+
+			if err := json.Unmarshal(stdout, &report); err != nil {
+				t.Fatalf("expected stdout to be the json report, got %v: %s", err, stdout)
+			}
+
+			if len(report.Repositories) != 1 || report.Repositories[0].Status != test.expected {
+				t.Errorf("expected a %s repository, got %s", test.expected, stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+// Package cmd is the entry point for cobra cli
+package cmd
+
+import (
+	"fuse/internal/providers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	giteaURL   string
+	giteaOwner string
+)
+
+var giteaCmd = &cobra.Command{
+	Use:   "gitea",
+	Short: "Fuse for Gitea and Forgejo",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun(cmd, "pat", "baseUrl", "owner")
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		return fuseRepositories(func(repository string) providers.Provider {
+			return &providers.Gitea{
+				Common:      commonInput(repository, giteaOwner),
+				PullRequest: pullRequestInput(),
+				BaseURL:     giteaURL,
+				Owner:       giteaOwner,
+			}
+		})
+	},
+}
+
+func init() {
+	giteaCmd.Flags().StringVarP(&giteaURL, "baseUrl", "u", "", "Gitea (or Forgejo) base url.")
+	giteaCmd.Flags().StringVarP(&giteaOwner, "owner", "o", "", "Gitea user or organization that owns the repository.")
+
+	_ = giteaCmd.MarkFlagRequired("owner")
+
+	rootCmd.AddCommand(giteaCmd)
+}
@@ -2,12 +2,9 @@
 package cmd
 
 import (
-	"fuse/internal/domain"
 	"fuse/internal/providers"
-	"fuse/internal/workflow"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
 )
 
 var (
@@ -18,43 +15,23 @@ var githubCmd = &cobra.Command{
 	Use:   "github",
 	Short: "Fuse for Github",
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		ConfigLog()
-
-		cmd.Flags().Visit(func(flag *pflag.Flag) {
-			if flag.Value.String() == "" {
-				panic("Found empty flag: " + flag.Name)
-			}
-		})
-		return nil
+		return preRun(cmd, "pat", "owner")
 	},
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		cmd.SilenceUsage = true
 
-		input := providers.GitHub{
-			Common: domain.CommonInput{
-				RepositoryName:   repoName,
-				Pat:              pat,
-				ContentDir:       contentDir,
-				Concurrency:      concurrency,
-				Tag:              tag,
-				CommentDelimiter: commentDelimiter,
-			},
-			PullRequest: domain.PullRequestInput{
-				Title:        prTitle,
-				AutoComplete: prAutoComplete,
-				Enabled:      prEnabled,
-			},
-			Owner: owner,
-		}
-
-		return workflow.Fuse(&input)
+		return fuseRepositories(func(repository string) providers.Provider {
+			return &providers.GitHub{
+				Common:      commonInput(repository, owner),
+				PullRequest: pullRequestInput(),
+				Owner:       owner,
+			}
+		})
 	},
 }
 
 func init() {
 	githubCmd.Flags().StringVarP(&owner, "owner", "o", "", "Github owner")
 
-	_ = githubCmd.MarkFlagRequired("owner")
-
 	rootCmd.AddCommand(githubCmd)
 }
@@ -0,0 +1,42 @@
+// Package cmd is the entry point for cobra cli
+package cmd
+
+import (
+	"fuse/internal/providers"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	gitlabURL string
+	namespace string
+)
+
+var gitlabCmd = &cobra.Command{
+	Use:   "gitlab",
+	Short: "Fuse for GitLab",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun(cmd, "pat", "namespace")
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+
+		return fuseRepositories(func(repository string) providers.Provider {
+			return &providers.GitLab{
+				Common:      commonInput(repository, namespace),
+				PullRequest: pullRequestInput(),
+				BaseURL:     gitlabURL,
+				Namespace:   namespace,
+			}
+		})
+	},
+}
+
+func init() {
+	gitlabCmd.Flags().StringVarP(&gitlabURL, "baseUrl", "u", providers.GitLabDefaultURL,
+		"GitLab base url. Use it to target self hosted instances.")
+	gitlabCmd.Flags().StringVarP(&namespace, "namespace", "n", "",
+		"GitLab namespace (user, group or group/subgroup) that owns the repository.")
+
+	rootCmd.AddCommand(gitlabCmd)
+}
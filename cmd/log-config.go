@@ -2,13 +2,19 @@
 package cmd
 
 import (
+	"encoding/json"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/rs/zerolog/pkgerrors"
 )
 
+// redacted replaces secrets in log lines
+const redacted = "[REDACTED]"
+
 // ConfigLog configures logzero log according to cli flags
 func ConfigLog() {
 	// set info log level
@@ -18,11 +24,54 @@ func ConfigLog() {
 		zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
 	}
 
+	// the personal access token is scrubbed from every log line, whatever logs it
+	var out io.Writer = newRedactWriter(os.Stderr, pat)
+
 	if prettyLogging {
-		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+		out = zerolog.ConsoleWriter{Out: out}
 	}
 
+	log.Logger = log.Output(out)
+
 	if logVerbose {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	}
 }
+
+// redactWriter replaces the secrets written to out with redacted
+type redactWriter struct {
+	out      io.Writer
+	replacer *strings.Replacer
+}
+
+// newRedactWriter returns a writer redacting the non empty secrets, as they are and json encoded, before writing to out
+func newRedactWriter(out io.Writer, secrets ...string) io.Writer {
+	var oldNew []string
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+
+		oldNew = append(oldNew, secret, redacted)
+
+		if encoded, err := json.Marshal(secret); err == nil && string(encoded[1:len(encoded)-1]) != secret {
+			oldNew = append(oldNew, string(encoded[1:len(encoded)-1]), redacted)
+		}
+	}
+
+	if len(oldNew) == 0 {
+		return out
+	}
+
+	return &redactWriter{out: out, replacer: strings.NewReplacer(oldNew...)}
+}
+
+// Write writes p to out without secrets. It reports the length of p as written, zerolog treats anything else as an error.
+func (w *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.replacer.Replace(string(p))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,168 @@
+// Package cmd is the entry point for cobra cli
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"fuse/internal/domain"
+	"fuse/internal/providers"
+	"fuse/internal/workflow"
+
+	"github.com/pkg/errors"
+)
+
+// fuseRepositories runs fuse for every target repository, given through --repoName or --reposFile.
+// newProvider builds the provider of each one of them.
+func fuseRepositories(newProvider func(repository string) providers.Provider) error {
+	repositories, err := targetRepositories()
+
+	if err != nil {
+		return err
+	}
+
+	// the report written to stdout must be the only output there
+	if reportFile == "-" {
+		workflow.Output, providers.Output = os.Stderr, os.Stderr
+	}
+
+	targets := make([]providers.Provider, 0, len(repositories))
+
+	for _, repository := range repositories {
+		targets = append(targets, newProvider(repository))
+	}
+
+	results, err := workflow.FuseAll(targets, parallelRepos)
+
+	if reportFile != "" {
+		if reportErr := workflow.WriteReport(results, reportFile); reportErr != nil {
+			return reportErr
+		}
+	}
+
+	return err
+}
+
+// targetRepositories merges repositories from the flags and the repositories file, keeping their order and dropping duplicates
+func targetRepositories() ([]string, error) {
+	repositories := append([]string{}, repoNames...)
+
+	if reposFile != "" {
+		f, err := os.Open(reposFile)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read repositories file")
+		}
+
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+
+			if line != "" && !strings.HasPrefix(line, "#") {
+				repositories = append(repositories, line)
+			}
+		}
+
+		if err = scanner.Err(); err != nil {
+			return nil, errors.Wrap(err, "Unable to read repositories file")
+		}
+	}
+
+	seen := map[string]bool{}
+	unique := make([]string, 0, len(repositories))
+
+	for _, repository := range repositories {
+		if !seen[repository] {
+			seen[repository] = true
+			unique = append(unique, repository)
+		}
+	}
+
+	if len(unique) == 0 {
+		return nil, errors.New("at least one target repository is required, use --repoName or --reposFile")
+	}
+
+	return unique, nil
+}
+
+// commonInput returns the cli inputs common to all providers for the given repository and its owner
+func commonInput(repository, owner string) domain.CommonInput {
+	return domain.CommonInput{
+		RepositoryName:   repository,
+		Owner:            owner,
+		BaseBranch:       baseBranch,
+		Pat:              pat,
+		ContentDir:       contentDir,
+		Concurrency:      concurrency,
+		Tag:              tag,
+		CommentDelimiter: commentDelimiter,
+		CommentStyles:    commentStyles,
+		DryRun:           dryRun,
+		StructuredMerge:  structuredMerge,
+		ListMerge:        listMerge,
+		Strategies:       strategies,
+		Vars:             templateVars(repository),
+		Include:          include,
+		Exclude:          exclude,
+		Symlinks:         symlinks,
+		GitClient:        gitClient,
+		Shallow:          shallow,
+		Sparse:           sparse,
+		SSHKey:           sshKey,
+		KnownHosts:       knownHosts,
+	}
+}
+
+// templateVars merges the --var template variables with the --repoVar ones of the repository, which take precedence.
+// Both are expected to be valid, see validateVars.
+func templateVars(repository string) map[string]string {
+	result := map[string]string{}
+
+	for _, v := range vars {
+		kv := strings.SplitN(v, "=", 2)
+		result[kv[0]] = kv[1]
+	}
+
+	for _, v := range repoVars {
+		repoKV := strings.SplitN(v, ":", 2)
+
+		if repoKV[0] == repository {
+			kv := strings.SplitN(repoKV[1], "=", 2)
+			result[kv[0]] = kv[1]
+		}
+	}
+
+	return result
+}
+
+// validateVars checks template variables are in the form key=value and repository ones in the form repository:key=value
+func validateVars() error {
+	for _, v := range vars {
+		if strings.Index(v, "=") <= 0 {
+			return errors.Errorf("invalid template variable %q, expected key=value", v)
+		}
+	}
+
+	for _, v := range repoVars {
+		repoKV := strings.SplitN(v, ":", 2)
+
+		if len(repoKV) != 2 || repoKV[0] == "" || strings.Index(repoKV[1], "=") <= 0 {
+			return errors.Errorf("invalid repository template variable %q, expected repository:key=value", v)
+		}
+	}
+
+	return nil
+}
+
+// pullRequestInput returns the cli inputs related to pull requests
+func pullRequestInput() domain.PullRequestInput {
+	return domain.PullRequestInput{
+		Title:        prTitle,
+		AutoComplete: prAutoComplete,
+		Enabled:      prEnabled,
+	}
+}
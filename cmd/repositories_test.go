@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplateVars(t *testing.T) {
+	vars = []string{"environment=staging", "region=eun", "url=https://a.io/?x=1"}
+	repoVars = []string{"service-a:region=euw", "service-b:replicas=2"}
+
+	defer func() {
+		vars, repoVars = nil, nil
+	}()
+
+	if err := validateVars(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"environment": "staging", "region": "euw", "url": "https://a.io/?x=1"}
+
+	if got := templateVars("service-a"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	for _, invalid := range [][]string{{"region"}, {"=eun"}} {
+		vars = invalid
+
+		if err := validateVars(); err == nil {
+			t.Errorf("expected %v to be invalid", invalid)
+		}
+	}
+
+	vars, repoVars = nil, []string{"region=eun"}
+
+	if err := validateVars(); err == nil {
+		t.Error("expected repository variables without repository to be invalid")
+	}
+}
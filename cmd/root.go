@@ -4,16 +4,41 @@ package cmd
 import (
 	"os"
 
+	"fuse/internal/core"
+	"fuse/internal/providers"
+
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	repoName         string
+	configFile       string
+	repoNames        []string
+	reposFile        string
+	parallelRepos    int
 	pat              string
 	tag              string
+	baseBranch       string
 	contentDir       string
 	commentDelimiter string
+	commentStyles    []string
 	concurrency      int8
+	dryRun           bool
+	structuredMerge  bool
+	listMerge        string
+	strategies       []string
+	vars             []string
+	repoVars         []string
+	include          []string
+	exclude          []string
+	symlinks         string
+	gitClient        string
+	shallow          bool
+	sparse           bool
+	sshKey           string
+	knownHosts       string
+	reportFile       string
 
 	prettyLogging  bool
 	logStackTraces bool
@@ -26,6 +51,7 @@ var (
 	rootCmd = &cobra.Command{
 		Use:   "fuse",
 		Short: "Fuse.",
+		RunE:  runConfiguredProvider,
 	}
 )
 
@@ -37,17 +63,77 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&repoName, "repoName", "r", "",
-		"Remote repository name to be used as fuse target.")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "f", "",
+		`Path to a fuse config file (yaml, json or toml), e.g. fuse.yaml. Keys are named after the flags and
+				command line flags take precedence. When running fuse without a provider command, the provider key selects it.`)
+	rootCmd.PersistentFlags().StringSliceVarP(&repoNames, "repoName", "r", nil,
+		"Remote repository name to be used as fuse target. Repeat it, or use a comma separated list, to target several repositories.")
+	rootCmd.PersistentFlags().StringVar(&reposFile, "reposFile", "",
+		"Path to a file listing target repository names, one per line. Empty lines and lines starting with # are ignored.")
+	rootCmd.PersistentFlags().IntVar(&parallelRepos, "parallelRepos", 4,
+		"Max number of repositories fused at the same time when targeting several repositories.")
 	rootCmd.PersistentFlags().StringVarP(&pat, "pat", "a", "",
 		"Personal access token to authenticate when performing actions.")
 	rootCmd.PersistentFlags().StringVarP(&contentDir, "contentDir", "d", "",
 		`Path to the directory that contains the content to be used by fuse in the target repository. 
 				The path may be relative to the current execution process directory (where you execute fuse) or an absolute path.`)
 	rootCmd.PersistentFlags().StringVarP(&tag, "tag", "v", "latest",
-		"Git tag to be used when committing to master.")
+		"Git tag to be used when committing straight to the base branch.")
+	rootCmd.PersistentFlags().StringVar(&baseBranch, "baseBranch", "",
+		"Branch fuse commits to, or targets with pull requests. Defaults to the repository default branch.")
 	rootCmd.PersistentFlags().StringVarP(&commentDelimiter, "commentDelimiter", "e", "//",
-		"Comment delimiter used for Fuse file mark.")
+		"Comment delimiter used for the fuse file mark of file types without a known comment style.")
+	rootCmd.PersistentFlags().StringArrayVar(&commentStyles, "commentStyle", nil,
+		`Comment style of a file extension, or name for files without one, in the form ext=prefix[,suffix], e.g. .xml=<!--,-->.
+				An empty prefix means the file type has no comments. Repeat it for several file types.`)
+
+	rootCmd.PersistentFlags().StringArrayVar(&include, "include", nil,
+		"Glob of the content files to apply, e.g. **/*.yaml. Repeat it for several globs. All files are applied by default.")
+	rootCmd.PersistentFlags().StringArrayVar(&exclude, "exclude", nil,
+		`Glob of the content files not to apply, e.g. *.swp, taking precedence over --include. Globs ending with / exclude
+				directories. Globs can also be listed, one per line, in a .fuseignore file at the content directory root. The .fuse
+				state directory is always excluded.`)
+
+	rootCmd.PersistentFlags().StringVar(&symlinks, "symlinks", core.SymlinksSkip,
+		`How symlinks in the content directory are handled: skip them with a warning, follow them applying the files they point
+				to, or preserve them as symlinks in the target repository, unless they point out of the content directory.`)
+
+	rootCmd.PersistentFlags().StringVar(&gitClient, "gitClient", "",
+		`Git implementation used to clone, commit and push: native, in process, shell, running the git binary, or api, reading
+				and committing only the content directory files through the github or azure devops api, without cloning.
+				Defaults to shell for azure devops and sparse clones, and native otherwise.`)
+	rootCmd.PersistentFlags().BoolVar(&shallow, "shallow", false,
+		"If enabled, only the last commit of the base branch is cloned.")
+	rootCmd.PersistentFlags().BoolVar(&sparse, "sparse", false,
+		`If enabled, only the files the content directory applies to are checked out, fetching their content on demand.
+				Sparse clones use the shell git client, other files are left untouched in the pushed commit.`)
+	rootCmd.PersistentFlags().StringVar(&sshKey, "sshKey", "",
+		`Path to a private key, e.g. a deploy key, to clone and push over ssh instead of https. The personal access token is
+				still used for the provider api.`)
+	rootCmd.PersistentFlags().StringVar(&knownHosts, "knownHosts", "",
+		"Path to the known_hosts file ssh host keys are checked against. Defaults to the user's known_hosts.")
+
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dryRun", false,
+		"If enabled, fuse prints the unified diff of every change instead of committing, pushing and creating pull requests.")
+
+	rootCmd.PersistentFlags().BoolVar(&structuredMerge, "structuredMerge", false,
+		`If enabled, yaml and json files are deep merged into the target documents instead of replacing their text. Merged yaml
+				files keep their comments and styles, but blank lines are dropped and lists are indented under their key.`)
+	rootCmd.PersistentFlags().StringVar(&listMerge, "listMerge", core.ListReplace,
+		"How the structured merge handles lists: replace, append missing items or key=<field> to merge items by field.")
+
+	rootCmd.PersistentFlags().StringArrayVar(&strategies, "strategy", nil,
+		`Strategy rule in the form glob=strategy choosing how matching files are applied: patch, overwrite, merge, append,
+				create, copy, delete or block. Repeat it for several rules, the first matching rule wins. Files matching none are patched.`)
+
+	rootCmd.PersistentFlags().StringArrayVar(&vars, "var", nil,
+		"Template variable in the form key=value, available to .fuse-tmpl content files as {{ .Vars.key }}. Repeat it for several.")
+	rootCmd.PersistentFlags().StringArrayVar(&repoVars, "repoVar", nil,
+		"Template variable of a single repository in the form repository:key=value. It takes precedence over --var.")
+
+	rootCmd.PersistentFlags().StringVar(&reportFile, "report", "",
+		`Path to write a json report of the run to, with the status of every repository and file. Use - to write it to stdout,
+				dry run diffs and git remote branches are then written to stderr.`)
 
 	rootCmd.PersistentFlags().BoolVarP(&prEnabled, "prEnabled", "i", false,
 		"If enabled, fuse will work in a new branch and create the associated pull request with the changes.")
@@ -64,8 +150,68 @@ func init() {
 		"If enabled and errors occur, stack traces will be shown.")
 	rootCmd.PersistentFlags().BoolVarP(&logVerbose, "verbose", "l", false,
 		"If enabled debug log level will be used.")
+}
+
+// preRun loads the config file, configures logging and validates the provided flags. Shared by all provider commands.
+func preRun(cmd *cobra.Command, required ...string) error {
+	if err := loadConfig(cmd); err != nil {
+		return err
+	}
+
+	ConfigLog()
+
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if flag.Value.String() == "" {
+			panic("Found empty flag: " + flag.Name)
+		}
+	})
+
+	if _, err := core.ParseListMerge(listMerge); err != nil {
+		return err
+	}
+
+	if _, err := core.ParseStrategyRules(strategies); err != nil {
+		return err
+	}
+
+	if _, err := core.ParseCommentStyles(commentStyles); err != nil {
+		return err
+	}
+
+	if err := validateVars(); err != nil {
+		return err
+	}
+
+	if err := core.ValidateSymlinks(symlinks); err != nil {
+		return err
+	}
+
+	if err := providers.ValidateGitClient(gitClient); err != nil {
+		return err
+	}
+
+	if sparse && gitClient == providers.GitClientNative {
+		return errors.New("sparse clones require the shell git client")
+	}
+
+	if sshKey != "" && gitClient == providers.GitClientAPI {
+		return errors.New("the api git client doesn't clone, --sshKey can't be used with it")
+	}
+
+	if knownHosts != "" && sshKey == "" {
+		return errors.New("--knownHosts requires --sshKey")
+	}
+
+	return requireFlags(cmd, append([]string{"contentDir"}, required...)...)
+}
+
+// requireFlags fails if any of the given flags was not provided, either through the command line or the config file.
+func requireFlags(cmd *cobra.Command, names ...string) error {
+	for _, name := range names {
+		if !cmd.Flags().Changed(name) {
+			return errors.New("required flag \"" + name + "\" not set")
+		}
+	}
 
-	_ = rootCmd.MarkFlagRequired("repoName")
-	_ = rootCmd.MarkFlagRequired("pat")
-	_ = rootCmd.MarkFlagRequired("contentDir")
+	return nil
 }
@@ -0,0 +1,94 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	blockBegin = " BEGIN managed by fuse"
+	blockEnd   = " END managed by fuse"
+)
+
+// block only touches the region of the original delimited by the fuse block markers, replacing it with the update.
+// The block is appended when the original doesn't have it yet.
+func (w *WorkItem) block() WorkItemResult {
+	updateContent, err := w.readUpdate()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	if w.Comment.Prefix == "" {
+		return errorResult(w, errors.New("Fuse blocks need a comment style, "+w.CommonPath+" doesn't support comments"))
+	}
+
+	if original == nil {
+		return w.result(nil, managedBlock(updateContent, w.Comment), "")
+	}
+
+	content, err := ReplaceBlock(*original, updateContent, w.Comment)
+
+	if err != nil {
+		return errorResult(w, errors.Wrap(err, "Unable to replace the fuse block of "+w.CommonPath))
+	}
+
+	return w.result(original, content, "")
+}
+
+// ReplaceBlock replaces the lines between the fuse block markers of content, markers included, with a block holding update.
+// When content has no markers the block is appended to it.
+func ReplaceBlock(content, update string, comment CommentStyle) (string, error) {
+	begin, end := comment.Comment(blockBegin), comment.Comment(blockEnd)
+	lines := strings.SplitAfter(content, "\n")
+	first, last := -1, -1
+
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case begin:
+			if first >= 0 {
+				return "", errors.New("found more than one fuse block")
+			}
+
+			first = i
+		case end:
+			if first < 0 || last >= 0 {
+				return "", errors.New("found a fuse block end without its begin")
+			}
+
+			last = i
+		}
+	}
+
+	block := managedBlock(update, comment)
+
+	if first < 0 {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+
+		return content + block, nil
+	}
+
+	if last < 0 {
+		return "", errors.New("found a fuse block begin without its end")
+	}
+
+	return strings.Join(lines[:first], "") + block + strings.Join(lines[last+1:], ""), nil
+}
+
+func managedBlock(update string, comment CommentStyle) string {
+	if update != "" && !strings.HasSuffix(update, "\n") {
+		update += "\n"
+	}
+
+	return comment.Comment(blockBegin) + "\n" + update + comment.Comment(blockEnd) + "\n"
+}
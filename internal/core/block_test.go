@@ -0,0 +1,49 @@
+package core
+
+import "testing"
+
+func TestReplaceBlock(t *testing.T) {
+	tests := []struct {
+		name, content, expected string
+	}{
+		{
+			name:     "appends the block",
+			content:  "bin/",
+			expected: "bin/\n# BEGIN managed by fuse\ndist/\n# END managed by fuse\n",
+		},
+		{
+			name:     "replaces the block",
+			content:  "bin/\n# BEGIN managed by fuse\nout/\ntmp/\n# END managed by fuse\n*.log\n",
+			expected: "bin/\n# BEGIN managed by fuse\ndist/\n# END managed by fuse\n*.log\n",
+		},
+		{
+			name:     "empty content",
+			content:  "",
+			expected: "# BEGIN managed by fuse\ndist/\n# END managed by fuse\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ReplaceBlock(test.content, "dist/", hashComment)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+
+	for _, content := range []string{
+		"# BEGIN managed by fuse\ndist/\n",
+		"dist/\n# END managed by fuse\n",
+		"# BEGIN managed by fuse\n# END managed by fuse\n# BEGIN managed by fuse\n# END managed by fuse\n",
+	} {
+		if _, err := ReplaceBlock(content, "dist/", hashComment); err == nil {
+			t.Errorf("expected %q to be rejected", content)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CommentStyle is the line comment syntax of a file type. Files that don't support comments, e.g. json, have no Prefix.
+type CommentStyle struct {
+	Prefix string
+	Suffix string
+}
+
+// Comment wraps text in a comment, e.g: <!-- text -->
+func (c CommentStyle) Comment(text string) string {
+	if c.Suffix == "" {
+		return c.Prefix + text
+	}
+
+	return c.Prefix + text + " " + c.Suffix
+}
+
+var (
+	hashComment  = CommentStyle{Prefix: "#"}
+	slashComment = CommentStyle{Prefix: "//"}
+	xmlComment   = CommentStyle{Prefix: "<!--", Suffix: "-->"}
+	dashComment  = CommentStyle{Prefix: "--"}
+	noComment    = CommentStyle{}
+)
+
+// commentStyles maps file extensions, or names for files without extension, to their comment style
+var commentStyles = map[string]CommentStyle{
+	".yaml":          hashComment,
+	".yml":           hashComment,
+	".sh":            hashComment,
+	".bash":          hashComment,
+	".toml":          hashComment,
+	".py":            hashComment,
+	".rb":            hashComment,
+	".tf":            hashComment,
+	".properties":    hashComment,
+	".gitignore":     hashComment,
+	".dockerignore":  hashComment,
+	".editorconfig":  hashComment,
+	"Makefile":       hashComment,
+	"Dockerfile":     hashComment,
+	"CODEOWNERS":     hashComment,
+	".go":            slashComment,
+	".js":            slashComment,
+	".ts":            slashComment,
+	".java":          slashComment,
+	".kt":            slashComment,
+	".cs":            slashComment,
+	".c":             slashComment,
+	".h":             slashComment,
+	".cpp":           slashComment,
+	".rs":            slashComment,
+	".swift":         slashComment,
+	".scala":         slashComment,
+	".groovy":        slashComment,
+	".xml":           xmlComment,
+	".html":          xmlComment,
+	".md":            xmlComment,
+	".csproj":        xmlComment,
+	".sql":           dashComment,
+	".lua":           dashComment,
+	".json":          noComment,
+	".gitattributes": hashComment,
+}
+
+// ParseCommentStyles parses comment style overrides in the form ext=prefix[,suffix], e.g: .xml=<!--,--> or Jenkinsfile=//.
+// Files without extension are matched by name. An empty prefix, e.g: .json=, means the file type has no comments.
+func ParseCommentStyles(values []string) (map[string]CommentStyle, error) {
+	styles := make(map[string]CommentStyle, len(values))
+
+	for _, value := range values {
+		i := strings.Index(value, "=")
+
+		if i <= 0 {
+			return nil, errors.Errorf("invalid comment style %q, expected ext=prefix[,suffix]", value)
+		}
+
+		style := CommentStyle{}
+		parts := strings.SplitN(value[i+1:], ",", 2)
+		style.Prefix = parts[0]
+
+		if len(parts) == 2 {
+			style.Suffix = parts[1]
+		}
+
+		styles[value[:i]] = style
+	}
+
+	return styles, nil
+}
+
+// CommentStyleOf returns the comment style of the file at filePath. overrides take precedence over the known file types and
+// fallback is used as the comment prefix of unknown ones.
+func CommentStyleOf(filePath string, overrides map[string]CommentStyle, fallback string) CommentStyle {
+	key := path.Ext(filePath)
+
+	if key == "" {
+		key = path.Base(filePath)
+	}
+
+	if style, ok := overrides[key]; ok {
+		return style
+	}
+
+	if style, ok := overrides[strings.TrimPrefix(key, ".")]; ok {
+		return style
+	}
+
+	if style, ok := commentStyles[key]; ok {
+		return style
+	}
+
+	return CommentStyle{Prefix: fallback}
+}
@@ -0,0 +1,36 @@
+package core
+
+import "testing"
+
+func TestCommentStyleOf(t *testing.T) {
+	overrides, err := ParseCommentStyles([]string{".xml=<!--,-->", "Jenkinsfile=//", ".txt=;", "json=#"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/values.yaml", "# managed by fuse"},
+		{"/main.go", "// managed by fuse"},
+		{"/pom.xml", "<!-- managed by fuse -->"},
+		{"/db/init.sql", "-- managed by fuse"},
+		{"/Makefile", "# managed by fuse"},
+		{"/Jenkinsfile", "// managed by fuse"},
+		{"/notes.txt", "; managed by fuse"},
+		{"/package.json", "# managed by fuse"},
+		{"/unknown.ext", "%% managed by fuse"},
+	}
+
+	for _, test := range tests {
+		if got := CommentStyleOf(test.path, overrides, "%%").Comment(" managed by fuse"); got != test.expected {
+			t.Errorf("expected %q for %s, got %q", test.expected, test.path, got)
+		}
+	}
+
+	if got := decorateUpdateContent("{}\n", CommentStyleOf("/package.json", nil, "//")); got != "{}\n" {
+		t.Errorf("expected json to be left undecorated, got %q", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -14,15 +15,60 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// CrawlResult stores the final result of the crawling process
+// CrawlResult stores the final result of the crawling process, including the result of every work item
 type CrawlResult struct {
 	WithDiffs uint32
 	Error     uint32
+	Results   []WorkItemResult
 }
 
-// Crawl traverses the provided directory tree structure looking for text files. It will not follow sym links.
-func Crawl(contentDir, targetDir, commentDelimiter string, concurrency int8) (chan *CrawlResult, error) {
-	var queue = make(chan WorkItem, concurrency)
+// CrawlOptions configures how the crawled files are applied to the target directory.
+// CommentStyles override the comment style of file types and CommentDelimiter is the comment prefix of unknown ones.
+// Rules select the strategy of each file, the first matching rule wins and files matching none are patched.
+// StructuredMerge adds rules deep merging yaml and json files, using ListMerge for lists.
+// Template is the data content files with the TemplateSuffix are rendered with.
+// Include and Exclude are globs filtering the crawled files, along with the IgnoreFile of the content directory.
+// Symlinks is how symlinks in the content directory are handled, see SymlinksSkip, SymlinksFollow and SymlinksPreserve.
+// IgnoreModes leaves the permission bits of the target files untouched, for targets that can't tell them.
+// ReadBlob reads the base of files changed since fuse applied them from the target repository, see State.
+type CrawlOptions struct {
+	CommentDelimiter string
+	CommentStyles    map[string]CommentStyle
+	Concurrency      int8
+	Rules            []StrategyRule
+	StructuredMerge  bool
+	ListMerge        ListMerge
+	Template         TemplateData
+	Include          []string
+	Exclude          []string
+	Symlinks         string
+	IgnoreModes      bool
+	ReadBlob         BlobReader
+}
+
+// Symlinks options
+const (
+	// SymlinksSkip skips symlinks, logging a warning. It's the default.
+	SymlinksSkip = "skip"
+	// SymlinksFollow applies the files symlinks point to as if they were at the symlink path
+	SymlinksFollow = "follow"
+	// SymlinksPreserve reproduces symlinks in the target, pointing to the same path
+	SymlinksPreserve = "preserve"
+)
+
+// ValidateSymlinks checks the symlinks option is empty or one of skip, follow or preserve
+func ValidateSymlinks(value string) error {
+	switch value {
+	case "", SymlinksSkip, SymlinksFollow, SymlinksPreserve:
+		return nil
+	}
+
+	return errors.Errorf("invalid symlinks option %q, expected skip, follow or preserve", value)
+}
+
+// Crawl traverses the provided directory tree structure looking for files to apply to the target directory.
+func Crawl(contentDir, targetDir string, opts CrawlOptions) (chan *CrawlResult, error) {
+	var queue = make(chan WorkItem, opts.Concurrency)
 
 	contentAbs, targetAbs, err := validatePaths(contentDir, targetDir)
 
@@ -30,11 +76,31 @@ func Crawl(contentDir, targetDir, commentDelimiter string, concurrency int8) (ch
 		return nil, errors.Wrap(err, "Crawling error")
 	}
 
+	if err = ValidateSymlinks(opts.Symlinks); err != nil {
+		return nil, errors.Wrap(err, "Crawling error")
+	}
+
+	filter, err := newPathFilter(contentAbs, opts.Include, opts.Exclude)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Crawling error")
+	}
+
+	if opts.StructuredMerge {
+		opts.Rules = append(append([]StrategyRule{}, opts.Rules...), structuredRules...)
+	}
+
+	state, err := LoadState(targetAbs, opts.ReadBlob)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Crawling error")
+	}
+
 	// start queue worker to consume work items
-	result := initWorker(queue)
+	result := initWorker(queue, state)
 
 	// crawl directory tree. This is the queue producer
-	err = crawlDirectory(contentAbs, targetAbs, commentDelimiter, queue)
+	err = crawlDirectory(contentAbs, targetAbs, opts, filter, state, queue)
 
 	if err != nil {
 		return nil, errors.Wrap(err, "Crawling error")
@@ -43,70 +109,300 @@ func Crawl(contentDir, targetDir, commentDelimiter string, concurrency int8) (ch
 	return result, nil
 }
 
+// ContentPaths returns the paths, relative to the target directory root, the content directory applies files to, including
+// the destinations of renames. It's used to clone only the files fuse touches.
+func ContentPaths(contentDir string, opts CrawlOptions) ([]string, error) {
+	contentAbs, err := filepath.Abs(contentDir)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Crawling error")
+	}
+
+	filter, err := newPathFilter(contentAbs, opts.Include, opts.Exclude)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Crawling error")
+	}
+
+	queue := make(chan WorkItem)
+	crawlErr := make(chan error, 1)
+
+	go func() {
+		crawlErr <- crawlDirectory(contentAbs, "", opts, filter, nil, queue)
+	}()
+
+	var paths []string
+
+	for w := range queue {
+		paths = append(paths, strings.TrimPrefix(w.CommonPath, "/"))
+
+		// invalid renames are reported when crawling
+		if w.Strategy == StrategyRename {
+			if newPath, err := w.renamePath(); err == nil {
+				paths = append(paths, strings.TrimPrefix(newPath, "/"))
+			}
+		}
+	}
+
+	if err = <-crawlErr; err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// crawler walks the content directory, putting a work item in the queue for each file to be applied
+type crawler struct {
+	contentAbs string
+	targetAbs  string
+	opts       CrawlOptions
+	filter     *pathFilter
+	state      *State
+	queue      chan WorkItem
+}
+
 // traverse the directory tree and for each valid file put it in the queue to be processed. Once done, close the queue channel.
-func crawlDirectory(contentAbs, targetAbs, commentDelimiter string, queue chan WorkItem) error {
+func crawlDirectory(contentAbs, targetAbs string, opts CrawlOptions, filter *pathFilter, state *State, queue chan WorkItem) error {
 	log.Debug().
 		Msg("Crawling: " + contentAbs)
 
 	// the walk is sync, once it's done tell the worker that no more that will be sent
 	defer close(queue)
 
-	// walk the directory tree
-	err := filepath.Walk(contentAbs, func(path string, info os.FileInfo, err error) error {
+	c := &crawler{
+		contentAbs: contentAbs,
+		targetAbs:  targetAbs,
+		opts:       opts,
+		filter:     filter,
+		state:      state,
+		queue:      queue,
+	}
+
+	realContentAbs, err := filepath.EvalSymlinks(contentAbs)
+
+	if err != nil {
+		return errors.Wrap(err, "Crawling error")
+	}
+
+	if err = c.walk(contentAbs, "", []string{realContentAbs}); err != nil {
+		return errors.Wrap(err, "Crawling error")
+	}
+
+	return nil
+}
+
+// walk the directory tree at root, whose files are at prefix in the target. followed holds the real paths of the directories
+// walked so far through symlinks, including the content directory, to detect loops.
+func (c *crawler) walk(root, prefix string, followed []string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return errors.Wrap(err, "Crawling error")
 		}
 
-		// skip directories
+		commonPath := prefix + strings.TrimPrefix(path, root)
+
+		// skip directories, and their content when excluded
 		if info.IsDir() {
+			if commonPath != "" && c.filter.skipDir(commonPath) {
+				return filepath.SkipDir
+			}
+
 			return nil
 		}
 
-		// skip empty files
-		if info.Size() == 0 {
+		if info.Mode()&os.ModeSymlink != 0 {
+			return c.symlink(path, commonPath, followed)
+		}
+
+		return c.enqueue(path, commonPath, info)
+	})
+}
+
+// symlink handles the symlink at path according to the symlinks option
+func (c *crawler) symlink(path, commonPath string, followed []string) error {
+	switch c.opts.Symlinks {
+	case SymlinksPreserve:
+		if c.filter.skipFile(commonPath) {
 			return nil
 		}
 
-		isText, err := util.IsTextFile(path)
+		linkTarget, err := os.Readlink(path)
 
 		if err != nil {
 			return errors.Wrap(err, "Crawling error")
 		}
 
-		// skip files that are not text based
-		if !isText {
-			log.Debug().
+		// links out of the content directory would point to paths of the machine running fuse once in the target
+		if !insideRoot(commonPath, linkTarget) {
+			log.Warn().
 				Str("filepath", path).
-				Msg("Skipping because its not text.")
+				Str("target", linkTarget).
+				Msg("Skipping symlink because its target is absolute or out of the content directory.")
 			return nil
 		}
 
-		commonPath := strings.Replace(path, contentAbs, "", -1)
-		wiID, err := uuid.NewRandom()
+		return c.push(WorkItem{
+			OriginalAbsPath: c.targetAbs + commonPath,
+			UpdateAbsPath:   path,
+			CommonPath:      commonPath,
+			LinkTarget:      linkTarget,
+		})
+	case SymlinksFollow:
+		resolved, err := filepath.EvalSymlinks(path)
+
+		if err != nil {
+			return errors.Wrap(err, "Unable to follow symlink "+commonPath)
+		}
+
+		info, err := os.Stat(resolved)
 
 		if err != nil {
 			return errors.Wrap(err, "Crawling error")
 		}
 
-		queue <- WorkItem{
-			OriginalAbsPath:  targetAbs + commonPath,
-			UpdateAbsPath:    path,
-			ID:               wiID.String(),
-			CommonPath:       commonPath,
-			CommentDelimiter: commentDelimiter,
+		if !info.IsDir() {
+			return c.enqueue(path, commonPath, info)
+		}
+
+		if c.filter.skipDir(commonPath) {
+			return nil
+		}
+
+		realParent, err := filepath.EvalSymlinks(filepath.Dir(path))
+
+		if err != nil {
+			return errors.Wrap(err, "Crawling error")
 		}
 
+		// following a link to a directory being walked, or to one of its parents, would never end
+		for _, dir := range append([]string{realParent}, followed...) {
+			if dir == resolved || strings.HasPrefix(dir, resolved+string(filepath.Separator)) {
+				log.Warn().
+					Str("filepath", path).
+					Str("target", resolved).
+					Msg("Skipping symlink because it creates a loop.")
+				return nil
+			}
+		}
+
+		return c.walk(resolved, commonPath, append(append([]string{}, followed...), resolved))
+	}
+
+	log.Warn().
+		Str("filepath", path).
+		Msg("Skipping symlink, use the follow or preserve symlinks option to apply it.")
+
+	return nil
+}
+
+// insideRoot tells if the link target, relative to the link at commonPath, stays within the content directory
+func insideRoot(commonPath, linkTarget string) bool {
+	if filepath.IsAbs(linkTarget) {
+		return false
+	}
+
+	resolved := filepath.Join(filepath.Dir(strings.TrimPrefix(commonPath, "/")), linkTarget)
+
+	return resolved != ".." && !strings.HasPrefix(resolved, ".."+string(filepath.Separator))
+}
+
+// enqueue puts the work item of the file at path in the queue, unless it's filtered
+func (c *crawler) enqueue(path, commonPath string, info os.FileInfo) error {
+	if c.filter.skipFile(commonPath) {
+		log.Debug().
+			Str("filepath", path).
+			Msg("Skipping because its excluded.")
 		return nil
+	}
+
+	var template *TemplateData
+
+	if strings.HasSuffix(commonPath, TemplateSuffix) {
+		commonPath = strings.TrimSuffix(commonPath, TemplateSuffix)
+		template = &c.opts.Template
+	}
+
+	strategy := SelectStrategy(c.opts.Rules, commonPath)
+
+	// tombstones delete or rename the file they are named after
+	switch {
+	case strings.HasSuffix(commonPath, DeleteSuffix):
+		commonPath = strings.TrimSuffix(commonPath, DeleteSuffix)
+		strategy = StrategyDelete
+	case strings.HasSuffix(commonPath, RenameSuffix):
+		commonPath = strings.TrimSuffix(commonPath, RenameSuffix)
+		strategy = StrategyRename
+	}
+
+	binary := false
+
+	// the content of files to be deleted doesn't matter and empty renames are reported as errors
+	if strategy != StrategyDelete && strategy != StrategyRename {
+		// skip empty files
+		if info.Size() == 0 {
+			return nil
+		}
+
+		isText, err := util.IsTextFile(path)
+
+		if err != nil {
+			return errors.Wrap(err, "Crawling error")
+		}
+
+		// files that are not text based are copied verbatim, unless only created
+		if !isText {
+			binary = true
+			template = nil
+
+			if textStrategies[strategy] {
+				log.Debug().
+					Str("filepath", path).
+					Msg("Copying because its not text.")
+
+				strategy = StrategyCopy
+			}
+		}
+	}
+
+	return c.push(WorkItem{
+		OriginalAbsPath: c.targetAbs + commonPath,
+		UpdateAbsPath:   path,
+		State:           c.state,
+		CommonPath:      commonPath,
+		Comment:         CommentStyleOf(commonPath, c.opts.CommentStyles, c.opts.CommentDelimiter),
+		Strategy:        strategy,
+		ListMerge:       c.opts.ListMerge,
+		Template:        template,
+		Mode:            c.mode(info),
+		Binary:          binary,
 	})
+}
+
+// mode returns the permission bits applied to the target file, none when modes are ignored
+func (c *crawler) mode(info os.FileInfo) os.FileMode {
+	if c.opts.IgnoreModes {
+		return 0
+	}
+
+	return info.Mode().Perm()
+}
+
+func (c *crawler) push(w WorkItem) error {
+	wiID, err := uuid.NewRandom()
 
 	if err != nil {
 		return errors.Wrap(err, "Crawling error")
 	}
 
+	w.ID = wiID.String()
+	c.queue <- w
+
 	return nil
 }
 
-func initWorker(queue chan WorkItem) chan *CrawlResult {
+func initWorker(queue chan WorkItem, state *State) chan *CrawlResult {
 	results := make(chan WorkItemResult)
 	done := make(chan *CrawlResult)
 	wg := sync.WaitGroup{}
@@ -126,7 +422,7 @@ func initWorker(queue chan WorkItem) chan *CrawlResult {
 						Interface("workItem", w).
 						Msg("Processing working item.")
 
-					result := w.ComputeDiffPatch()
+					result := w.Apply()
 
 					// write the result to the original destination
 					if result.Err == nil {
@@ -138,8 +434,17 @@ func initWorker(queue chan WorkItem) chan *CrawlResult {
 					results <- result
 				}(wi)
 			} else {
-				// when there are no more WI we need to wait for all results to be computed
+				// when there are no more WI we need to wait for all results to be computed, recording their state once
 				wg.Wait()
+
+				if err := state.Save(); err != nil {
+					log.Error().
+						Err(err).
+						Msg("Unable to save the fuse state.")
+					toReturn.Error++
+					toReturn.Results = append(toReturn.Results, WorkItemResult{CommonPath: "/" + StateFile, Err: err})
+				}
+
 				done <- toReturn
 			}
 		}
@@ -147,6 +452,8 @@ func initWorker(queue chan WorkItem) chan *CrawlResult {
 
 	go func() {
 		for res := range results {
+			toReturn.Results = append(toReturn.Results, res)
+
 			if res.Err != nil {
 				toReturn.Error++
 				log.Error().
@@ -1 +1,223 @@
 package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestCrawlSymlinks(t *testing.T) {
+	root, err := ioutil.TempDir("", "fuse-symlinks")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	content, shared := filepath.Join(root, "content"), filepath.Join(root, "shared")
+
+	for path, text := range map[string]string{
+		"content/values.yaml":      "a: 1\n",
+		"shared/common/app.yaml":   "b: 1\n",
+		"shared/common/other.yaml": "c: 1\n",
+	} {
+		path = filepath.Join(root, path)
+
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for link, target := range map[string]string{
+		"content/common":    filepath.Join(shared, "common"),
+		"content/app.yaml":  "common/app.yaml",
+		"content/link.yaml": "values.yaml",
+		"content/up.yaml":   "../shared/common/app.yaml",
+		"shared/common/top": content,
+	} {
+		if err = os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		symlinks string
+		expected []string
+	}{
+		{SymlinksSkip, []string{"/values.yaml"}},
+		{SymlinksFollow, []string{"/app.yaml", "/common/app.yaml", "/common/other.yaml", "/link.yaml", "/up.yaml", "/values.yaml"}},
+		// the absolute common and the relative up.yaml links leave the content directory
+		{SymlinksPreserve, []string{"/app.yaml", "/link.yaml", "/values.yaml"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.symlinks, func(t *testing.T) {
+			target, err := ioutil.TempDir("", "fuse-target")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer os.RemoveAll(target)
+
+			results, err := Crawl(content, target, CrawlOptions{Concurrency: 2, Symlinks: test.symlinks})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var paths []string
+
+			for _, result := range (<-results).Results {
+				if result.Err != nil {
+					t.Error(result.Err)
+				}
+
+				paths = append(paths, result.CommonPath)
+			}
+
+			sort.Strings(paths)
+
+			if len(paths) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, paths)
+			}
+
+			for i := range paths {
+				if paths[i] != test.expected[i] {
+					t.Errorf("expected %v, got %v", test.expected, paths)
+				}
+			}
+
+			if test.symlinks == SymlinksPreserve {
+				if link, err := os.Readlink(filepath.Join(target, "app.yaml")); err != nil || link != "common/app.yaml" {
+					t.Errorf("expected app.yaml to be a symlink to common/app.yaml, got %q %v", link, err)
+				}
+			}
+		})
+	}
+}
+
+func TestContentPaths(t *testing.T) {
+	content, err := ioutil.TempDir("", "fuse-content")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(content)
+
+	for path, text := range map[string]string{
+		"charts/app/values.yaml.fuse-tmpl": "name: {{ .Repository }}\n",
+		"legacy.conf.fuse-delete":          "",
+		"old.yaml.fuse-rename":             "config/new.yaml\n",
+		"drafts/notes.md":                  "wip\n",
+		"empty.txt":                        "",
+	} {
+		path = filepath.Join(content, path)
+
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := ContentPaths(content, CrawlOptions{Exclude: []string{"drafts/"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"charts/app/values.yaml", "config/new.yaml", "legacy.conf", "old.yaml"}
+
+	if len(paths) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, paths)
+		}
+	}
+}
+
+func TestInsideRoot(t *testing.T) {
+	tests := []struct {
+		commonPath, linkTarget string
+		expected               bool
+	}{
+		{"/app.yaml", "values.yaml", true},
+		{"/charts/app.yaml", "../values.yaml", true},
+		{"/charts/app.yaml", "./sub/../values.yaml", true},
+		{"/app.yaml", "../shared/app.yaml", false},
+		{"/charts/app.yaml", "../../app.yaml", false},
+		{"/app.yaml", "..", false},
+		{"/common", "/tmp/shared/common", false},
+	}
+
+	for _, test := range tests {
+		if got := insideRoot(test.commonPath, test.linkTarget); got != test.expected {
+			t.Errorf("insideRoot(%q, %q) = %v, expected %v", test.commonPath, test.linkTarget, got, test.expected)
+		}
+	}
+}
+
+func TestCrawlBinaryCreate(t *testing.T) {
+	content, err := ioutil.TempDir("", "fuse-content")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(content)
+
+	target, err := ioutil.TempDir("", "fuse-target")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(target)
+
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x01"
+
+	for _, name := range []string{"existing.png", "new.png"} {
+		if err = ioutil.WriteFile(filepath.Join(content, name), []byte(binary), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(target, "existing.png"), []byte("kept\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Crawl(content, target, CrawlOptions{
+		Concurrency: 2,
+		Rules:       []StrategyRule{{Glob: "*.png", Strategy: StrategyCreate}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range (<-results).Results {
+		if result.Err != nil {
+			t.Error(result.Err)
+		}
+	}
+
+	for name, expected := range map[string]string{"existing.png": "kept\x00", "new.png": binary} {
+		if got, err := ioutil.ReadFile(filepath.Join(target, name)); err != nil || string(got) != expected {
+			t.Errorf("expected %s to be %q, got %q %v", name, expected, got, err)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -15,29 +16,132 @@ var touchedByFuse = " managed by fuse"
 
 // WorkItem represents an intended pair of content to be patched. An original absolute destination pointing to the initial version
 // and an absolute path pointing to the updated content to be patched in the original destination.
+// State holds the content fuse last applied to the original destination, if any.
+// Comment is the comment style used to mark the content as managed by fuse.
+// Strategy names the strategy used to apply the update, see Apply. ListMerge is used by the structured merge.
+// Template is the data the update is rendered with, nil when the update is not a template.
+// Mode holds the permission bits of the update, applied to the original, and Binary is true when the update is not text.
+// LinkTarget is set when the update is a symlink to be reproduced as is.
 type WorkItem struct {
-	OriginalAbsPath  string
-	UpdateAbsPath    string
-	CommonPath       string
-	ID               string
-	CommentDelimiter string
+	OriginalAbsPath string
+	UpdateAbsPath   string
+	State           *State `json:"-"`
+	CommonPath      string
+	ID              string
+	Comment         CommentStyle
+	Strategy        string
+	ListMerge       ListMerge
+	Template        *TemplateData
+	Mode            os.FileMode
+	Binary          bool
+	LinkTarget      string
 }
 
 // WorkItemResult represents the result of a WorkItem.
-// It contains the Diff and Patches between the original and the update.
+// It contains the patched ResultText and Diff, a line based unified diff between the original and the patched content.
+// Created is true when the original didn't exist, Deleted when it is to be removed, Renamed when it is to be moved to
+// RenamedAbsPath, RenamedPath relative to the repository root, unless it already was, and Conflict when the update could not
+// be merged.
+// Mode holds the permission bits ResultText is written with, 0 to leave them untouched.
+// LinkTarget is set when the original is to be replaced by a symlink to it.
+// BaseText is the content to be recorded in State as the base of the next three-way merge.
 // If an error occurred processing the associated work item, err will contain the error.
 type WorkItemResult struct {
 	WorkItemID      string
 	OriginalAbsPath string
 	UpdateAbsPath   string
+	RenamedAbsPath  string
+	RenamedPath     string
+	LinkTarget      string
+	CommonPath      string
 	ResultText      string
+	Mode            os.FileMode
+	BaseText        string
+	State           *State `json:"-"`
+	Diff            string
 	Err             error
 	HasDiffs        bool
+	Created         bool
+	Deleted         bool
+	Renamed         bool
+	Conflict        bool
+}
+
+// Write serializes the WorkItemResult content at wr.OriginalAbsPath and, when it changed, records the applied content in
+// wr.State. Deleted results remove both and renamed results move them instead.
+func (wr *WorkItemResult) Write() error {
+	if wr.LinkTarget != "" {
+		if !wr.HasDiffs {
+			return nil
+		}
+
+		if err := os.Remove(wr.OriginalAbsPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Symlink result error")
+		}
+
+		if err := os.MkdirAll(filepath.Dir(wr.OriginalAbsPath), os.ModePerm); err != nil {
+			return errors.Wrap(err, "Symlink result error")
+		}
+
+		return errors.Wrap(os.Symlink(wr.LinkTarget, wr.OriginalAbsPath), "Symlink result error")
+	}
+
+	if wr.Renamed {
+		if !wr.HasDiffs {
+			return nil
+		}
+
+		if err := moveFile(wr.OriginalAbsPath, wr.RenamedAbsPath); err != nil {
+			return err
+		}
+
+		if wr.State != nil {
+			wr.State.Rename(wr.CommonPath, wr.RenamedPath)
+		}
+
+		return nil
+	}
+
+	if wr.Deleted {
+		if err := os.Remove(wr.OriginalAbsPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Delete result error")
+		}
+
+		if wr.State != nil {
+			wr.State.Remove(wr.CommonPath)
+		}
+
+		return nil
+	}
+
+	if err := writeFile(wr.OriginalAbsPath, wr.ResultText, wr.Mode); err != nil {
+		return err
+	}
+
+	// unchanged files keep their record, still matching their content
+	if wr.State != nil && wr.BaseText != "" && wr.HasDiffs {
+		wr.State.Record(wr.CommonPath, wr.BaseText, wr.ResultText)
+	}
+
+	return nil
 }
 
-// Write serializes the WorkItemResult content at wr.OriginalAbsPath
-func (wr *WorkItemResult) Write() (err error) {
-	path := wr.OriginalAbsPath[:strings.LastIndex(wr.OriginalAbsPath, "/")]
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(to[:strings.LastIndex(to, "/")], os.ModePerm); err != nil {
+		return errors.Wrap(err, "Unable to create folder for "+to)
+	}
+
+	if err := os.Rename(from, to); err != nil {
+		return errors.Wrap(err, "Rename result error")
+	}
+
+	return nil
+}
+
+// writeFile writes content to filePath, setting its permission bits to mode unless it's 0. New files are otherwise created
+// with 0644 and existing ones keep theirs.
+func writeFile(filePath, content string, mode os.FileMode) (err error) {
+	path := filePath[:strings.LastIndex(filePath, "/")]
 
 	// check if the directory path of the file exists. Create it if it doesn't exist.
 	if _, err = os.Stat(path); os.IsNotExist(err) {
@@ -48,13 +152,13 @@ func (wr *WorkItemResult) Write() (err error) {
 	}
 
 	// write only mode and truncate the file to 0 bytes before writing
-	f, err := os.OpenFile(wr.OriginalAbsPath, os.O_TRUNC|os.O_RDWR|os.O_APPEND|os.O_CREATE, os.ModePerm)
+	f, err := os.OpenFile(filePath, os.O_TRUNC|os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
 
 	if err != nil {
 		return errors.Wrap(err, "Write result error")
 	}
 
-	n, err := f.Write([]byte(wr.ResultText))
+	n, err := f.Write([]byte(content))
 
 	if err != nil {
 		return errors.Wrap(err, "Write result error")
@@ -73,9 +177,16 @@ func (wr *WorkItemResult) Write() (err error) {
 		return errors.Wrap(err, "Close fd on result write")
 	}
 
+	// the open mode only applies to new files and is subject to the umask
+	if mode != 0 {
+		if err = os.Chmod(filePath, mode); err != nil {
+			return errors.Wrap(err, "Unable to set the mode of "+filePath)
+		}
+	}
+
 	log.Debug().
 		Int("totalBytes", n).
-		Str("file", wr.OriginalAbsPath).
+		Str("file", filePath).
 		Msg("Successfully written patch.")
 
 	return nil
@@ -86,11 +197,12 @@ func (w *WorkItem) ComputeDiffPatch() (result WorkItemResult) {
 	//TODO: improve this approach to contemplate big files. Chunk by chunk read and compare
 
 	// 1. read update content
-	updateContent, err := ioutil.ReadFile(w.UpdateAbsPath)
+	updateContent, err := w.readUpdate()
 	if err != nil {
 		return errorResult(w, err)
 	}
-	decoratedContent := decorateUpdateContent(string(updateContent), w.CommentDelimiter)
+
+	decoratedContent := decorateUpdateContent(updateContent, w.Comment)
 
 	// 2. if the file doesn't exist in the target repo don't compute anything
 	_, err = os.Stat(w.OriginalAbsPath)
@@ -99,9 +211,14 @@ func (w *WorkItem) ComputeDiffPatch() (result WorkItemResult) {
 			WorkItemID:      w.ID,
 			OriginalAbsPath: w.OriginalAbsPath,
 			UpdateAbsPath:   w.UpdateAbsPath,
+			State:           w.State,
+			CommonPath:      w.CommonPath,
 			ResultText:      decoratedContent,
+			BaseText:        decoratedContent,
+			Diff:            UnifiedDiff(w.CommonPath, nil, decoratedContent),
 			Err:             nil,
 			HasDiffs:        true,
+			Created:         true,
 		}
 	}
 	// 3. Read the original file
@@ -112,6 +229,14 @@ func (w *WorkItem) ComputeDiffPatch() (result WorkItemResult) {
 		return errorResult(w, err)
 	}
 
+	// 4. three-way merge with the content applied last time, keeping the changes made to the file since then.
+	// Files without a recorded base, e.g: the first time fuse patches them, are patched as a whole.
+	if w.State != nil {
+		if baseContent, known := w.State.Base(w.CommonPath, originalContent); known {
+			return w.merge(baseContent, originalContent, decoratedContent)
+		}
+	}
+
 	log.Info().
 		Str("originalFile", w.OriginalAbsPath).
 		Str("updateFile", w.UpdateAbsPath).
@@ -119,7 +244,7 @@ func (w *WorkItem) ComputeDiffPatch() (result WorkItemResult) {
 		Interface("decoratedContent", decoratedContent).
 		Msg("About to diff and patch")
 
-	// 4. compute diffs and patches
+	// 5. compute diffs and patches
 	dmp := diffmatchpatch.New()
 	diffs := dmp.DiffMain(originalContent, decoratedContent, true)
 	patches := dmp.PatchMake(diffs)
@@ -141,25 +266,116 @@ func (w *WorkItem) ComputeDiffPatch() (result WorkItemResult) {
 		}
 	}
 
+	unifiedDiff := UnifiedDiff(w.CommonPath, &originalContent, patchResult)
+
+	log.Debug().
+		Str("file", w.OriginalAbsPath).
+		Str("diff", unifiedDiff).
+		Msg("Unified diff")
+
 	return WorkItemResult{
 		WorkItemID:      w.ID,
 		OriginalAbsPath: w.OriginalAbsPath,
 		UpdateAbsPath:   w.UpdateAbsPath,
+		State:           w.State,
+		CommonPath:      w.CommonPath,
 		ResultText:      patchResult,
+		BaseText:        decoratedContent,
+		Diff:            unifiedDiff,
 		Err:             nil,
 		HasDiffs:        hasDiffs,
 	}
 }
 
+// merge performs the three-way merge between the recorded base, the original and the decorated update.
+// Conflicts are reported as errors, the original is left untouched.
+func (w *WorkItem) merge(base, original, decorated string) WorkItemResult {
+	log.Info().
+		Str("originalFile", w.OriginalAbsPath).
+		Str("updateFile", w.UpdateAbsPath).
+		Msg("About to merge")
+
+	merged, conflicts := Merge3(base, original, decorated)
+	diff := UnifiedDiff(w.CommonPath, &original, merged)
+
+	if conflicts > 0 {
+		result := errorResult(w, errors.Errorf("%d merge conflicts between %s and the update", conflicts, w.CommonPath))
+		result.Conflict = true
+		result.Diff = diff
+
+		return result
+	}
+
+	return WorkItemResult{
+		WorkItemID:      w.ID,
+		OriginalAbsPath: w.OriginalAbsPath,
+		UpdateAbsPath:   w.UpdateAbsPath,
+		State:           w.State,
+		CommonPath:      w.CommonPath,
+		ResultText:      merged,
+		BaseText:        decorated,
+		Diff:            diff,
+		Err:             nil,
+		HasDiffs:        merged != original,
+	}
+}
+
+// structuredMerge deep merges the update into the original document. Documents can't be marked as managed by fuse,
+// a comment would not be valid json, and there's no base recorded as the merge only touches the keys in the update.
+func (w *WorkItem) structuredMerge() WorkItemResult {
+	updateContent, err := w.readUpdate()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	if original == nil {
+		return w.result(nil, updateContent, "")
+	}
+
+	merged, err := StructuredMerge(w.CommonPath, *original, updateContent, w.ListMerge)
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	return w.result(original, merged, "")
+}
+
 func errorResult(w *WorkItem, err error) WorkItemResult {
 	return WorkItemResult{
 		Err:             err,
 		WorkItemID:      w.ID,
 		OriginalAbsPath: w.OriginalAbsPath,
 		UpdateAbsPath:   w.UpdateAbsPath,
+		CommonPath:      w.CommonPath,
 	}
 }
 
-func decorateUpdateContent(content, commentDelimiter string) string {
-	return commentDelimiter + touchedByFuse + "\n" + content
+// decorateUpdateContent marks the content as managed by fuse, unless its file type doesn't support comments.
+// The mark goes after a leading shebang or xml declaration, which must stay on the first line.
+func decorateUpdateContent(content string, comment CommentStyle) string {
+	if comment.Prefix == "" {
+		return content
+	}
+
+	mark := comment.Comment(touchedByFuse) + "\n"
+
+	if !strings.HasPrefix(content, "#!") && !strings.HasPrefix(content, "<?xml") {
+		return mark + content
+	}
+
+	i := strings.Index(content, "\n")
+
+	if i < 0 {
+		return content + "\n" + mark
+	}
+
+	return content[:i+1] + mark + content[i+1:]
 }
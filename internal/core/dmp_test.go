@@ -1 +1,62 @@
 package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteModes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuse-write")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing.sh")
+
+	if err = ioutil.WriteFile(existing, []byte("echo\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState(dir, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []WorkItemResult{
+		{OriginalAbsPath: filepath.Join(dir, "run.sh"), CommonPath: "/run.sh", ResultText: "echo\n", Mode: 0755,
+			State: state, BaseText: "echo\n", HasDiffs: true},
+		{OriginalAbsPath: filepath.Join(dir, "values.yaml"), ResultText: "a: 1\n"},
+		{OriginalAbsPath: existing, ResultText: "echo changed\n"},
+	}
+
+	for _, result := range results {
+		if err = result.Write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]os.FileMode{
+		"run.sh":      0755,
+		StateFile:     0644,
+		"values.yaml": 0644,
+		"existing.sh": 0755,
+	} {
+		info, err := os.Stat(filepath.Join(dir, path))
+
+		if err != nil {
+			t.Error(err)
+		} else if info.Mode().Perm() != expected {
+			t.Errorf("expected %s to have mode %v, got %v", path, expected, info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IgnoreFile lists globs, one per line, of content files that are not crawled. It lives at the content directory root.
+const IgnoreFile = ".fuseignore"
+
+// pathFilter decides which content files are crawled. Files are crawled when they match any include glob, or there are none,
+// and don't match any exclude glob. Globs ending with / only match directories. The ignore file and the StateDir, e.g: of a
+// content directory copied from a target repository, are always excluded.
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+func newPathFilter(contentAbs string, include, exclude []string) (*pathFilter, error) {
+	filter := &pathFilter{
+		include: include,
+		exclude: append([]string{"/" + IgnoreFile, "/" + StateDir + "/"}, exclude...),
+	}
+
+	f, err := os.Open(filepath.Join(contentAbs, IgnoreFile))
+
+	if os.IsNotExist(err) {
+		return filter, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read "+IgnoreFile)
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line != "" && !strings.HasPrefix(line, "#") {
+			filter.exclude = append(filter.exclude, line)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Unable to read "+IgnoreFile)
+	}
+
+	return filter, nil
+}
+
+// skipDir checks if the directory at commonPath is excluded
+func (f *pathFilter) skipDir(commonPath string) bool {
+	for _, glob := range f.exclude {
+		if MatchGlob(strings.TrimSuffix(glob, "/"), commonPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipFile checks if the file at commonPath is not included or is excluded
+func (f *pathFilter) skipFile(commonPath string) bool {
+	for _, glob := range f.exclude {
+		if !strings.HasSuffix(glob, "/") && MatchGlob(glob, commonPath) {
+			return true
+		}
+	}
+
+	if len(f.include) == 0 {
+		return false
+	}
+
+	for _, glob := range f.include {
+		if MatchGlob(glob, commonPath) {
+			return false
+		}
+	}
+
+	return true
+}
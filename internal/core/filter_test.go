@@ -0,0 +1,73 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestCrawlFilters(t *testing.T) {
+	content, err := ioutil.TempDir("", "fuse-content")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(content)
+
+	target, err := ioutil.TempDir("", "fuse-target")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(target)
+
+	for path, text := range map[string]string{
+		IgnoreFile:             "# editor files\n*.swp\ndrafts/\n",
+		"README.md":            "for humans\n",
+		"values.yaml":          "a: 1\n",
+		".values.yaml.swp":     "swap\n",
+		"drafts/values.yaml":   "a: 2\n",
+		"charts/values.yaml":   "a: 3\n",
+		"charts/notes.txt":     "notes\n",
+		"charts/skip/app.yaml": "a: 4\n",
+		".fuse/values.yaml":    "a: 0\n",
+	} {
+		path = filepath.Join(content, path)
+
+		if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Crawl(content, target, CrawlOptions{
+		CommentDelimiter: "#",
+		Concurrency:      2,
+		Include:          []string{"*.yaml"},
+		Exclude:          []string{"charts/skip/"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+
+	for _, result := range (<-results).Results {
+		paths = append(paths, result.CommonPath)
+	}
+
+	sort.Strings(paths)
+
+	if expected := []string{"/charts/values.yaml", "/values.yaml"}; len(paths) != 2 || paths[0] != expected[0] ||
+		paths[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,134 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Merge3 performs a line based three-way merge between current and update, which both derive from base.
+// Changes made on either side are kept. Overlapping changes that differ are conflicts, which are counted and
+// written to the merged content between git style conflict markers.
+func Merge3(base, current, update string) (merged string, conflicts int) {
+	baseLines := splitLines(base)
+	currentLines := splitLines(current)
+	updateLines := splitLines(update)
+	currentMatches := matchLines(base, current, len(baseLines))
+	updateMatches := matchLines(base, update, len(baseLines))
+
+	var out strings.Builder
+	i, c, u := 0, 0, 0
+
+	for {
+		// copy the lines that are unchanged on both sides
+		for i < len(baseLines) && currentMatches[i] == c && updateMatches[i] == u {
+			out.WriteString(baseLines[i])
+			i, c, u = i+1, c+1, u+1
+		}
+
+		if i == len(baseLines) && c == len(currentLines) && u == len(updateLines) {
+			break
+		}
+
+		// the changed chunk ends on the next base line kept by both sides
+		j := i
+		for j < len(baseLines) && (currentMatches[j] == -1 || updateMatches[j] == -1) {
+			j++
+		}
+
+		currentEnd, updateEnd := len(currentLines), len(updateLines)
+
+		if j < len(baseLines) {
+			currentEnd, updateEnd = currentMatches[j], updateMatches[j]
+		}
+
+		baseChunk := baseLines[i:j]
+		currentChunk := currentLines[c:currentEnd]
+		updateChunk := updateLines[u:updateEnd]
+
+		switch {
+		case equalLines(currentChunk, baseChunk):
+			writeLines(&out, updateChunk)
+		case equalLines(updateChunk, baseChunk), equalLines(currentChunk, updateChunk):
+			writeLines(&out, currentChunk)
+		default:
+			conflicts++
+			out.WriteString("<<<<<<< current\n")
+			writeLines(&out, currentChunk)
+			out.WriteString("=======\n")
+			writeLines(&out, updateChunk)
+			out.WriteString(">>>>>>> fuse\n")
+		}
+
+		i, c, u = j, currentEnd, updateEnd
+	}
+
+	return out.String(), conflicts
+}
+
+// matchLines maps each line of base to the index of the same line in other, or -1 if it was changed or removed
+func matchLines(base, other string, baseLen int) []int {
+	dmp := diffmatchpatch.New()
+	baseChars, otherChars, lineArray := dmp.DiffLinesToChars(base, other)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(baseChars, otherChars, false), lineArray)
+
+	matches := make([]int, baseLen)
+	i, o := 0, 0
+
+	for _, diff := range diffs {
+		count := len(splitLines(diff.Text))
+
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			for k := 0; k < count; k++ {
+				matches[i] = o
+				i, o = i+1, o+1
+			}
+		case diffmatchpatch.DiffDelete:
+			for k := 0; k < count; k++ {
+				matches[i] = -1
+				i++
+			}
+		case diffmatchpatch.DiffInsert:
+			o += count
+		}
+	}
+
+	return matches
+}
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+
+		// keep conflict markers on their own line
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package core
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+
+	tests := []struct {
+		name      string
+		current   string
+		update    string
+		expected  string
+		conflicts int
+	}{
+		{"unchanged", base, base, base, 0},
+		{"only update", base, "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", 0},
+		{"local edit kept", "a\nb\nc\nd\ne\nlocal\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\nlocal\n", 0},
+		{"both sides", "local\na\nb\nc\nd\nE\n", "a\nB\nc\nd\ne\n", "local\na\nB\nc\nd\nE\n", 0},
+		{"same change", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", 0},
+		{"removed line", "a\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "a\nc\nd\nE\n", 0},
+		{"conflict", "a\nlocal\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\n<<<<<<< current\nlocal\n=======\nB\n>>>>>>> fuse\nc\nd\ne\n", 1},
+	}
+
+	for _, test := range tests {
+		merged, conflicts := Merge3(base, test.current, test.update)
+
+		if merged != test.expected || conflicts != test.conflicts {
+			t.Errorf("%s: expected %d conflicts and %q, got %d and %q", test.name, test.conflicts, test.expected, conflicts, merged)
+		}
+	}
+}
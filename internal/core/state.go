@@ -0,0 +1,184 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// StateDir is the directory, relative to the target repository root, holding the StateFile
+const StateDir = ".fuse"
+
+// StateFile is the file, relative to the target repository root, where fuse records the content it last applied to each file.
+// It's the base of the three-way merges of the next run.
+const StateFile = StateDir + "/state.json"
+
+// StateEntry records the content fuse last applied to a file. Blob is the git blob sha of the content, both its hash and the
+// reference it's read from in the repository history. Content holds the content itself only when it was merged with local
+// changes, as it was then never committed at the file path.
+type StateEntry struct {
+	Blob    string `json:"blob"`
+	Content string `json:"content,omitempty"`
+}
+
+// BlobReader reads a git blob of the target repository by its sha
+type BlobReader func(sha string) ([]byte, error)
+
+// State is the content fuse last applied to the files of the target repository, keyed by their path relative to its root.
+// It's safe for concurrent use by the work items of a crawl.
+type State struct {
+	Files map[string]StateEntry `json:"files"`
+
+	path     string
+	readBlob BlobReader
+	changed  bool
+	mutex    sync.Mutex
+}
+
+// LoadState reads the StateFile of the target directory, an empty state if there's none. readBlob, if any, reads the base of
+// files changed since fuse applied them.
+func LoadState(targetAbs string, readBlob BlobReader) (*State, error) {
+	state := &State{
+		Files:    map[string]StateEntry{},
+		path:     filepath.Join(targetAbs, StateFile),
+		readBlob: readBlob,
+	}
+
+	content, err := ioutil.ReadFile(state.path)
+
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read "+StateFile)
+	}
+
+	if err = json.Unmarshal(content, state); err != nil {
+		return nil, errors.Wrap(err, "Unable to parse "+StateFile)
+	}
+
+	if state.Files == nil {
+		state.Files = map[string]StateEntry{}
+	}
+
+	return state, nil
+}
+
+// Base returns the content fuse last applied to the file at commonPath, given its current content, and whether it's known.
+// The file content is the base as long as it wasn't changed since, otherwise it's read from the repository history.
+func (s *State) Base(commonPath, original string) (string, bool) {
+	s.mutex.Lock()
+	entry, exists := s.Files[stateKey(commonPath)]
+	s.mutex.Unlock()
+
+	switch {
+	case !exists:
+		return "", false
+	case entry.Content != "":
+		return entry.Content, true
+	case BlobSHA(original) == entry.Blob:
+		return original, true
+	case s.readBlob == nil:
+		return "", false
+	}
+
+	content, err := s.readBlob(entry.Blob)
+
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("file", commonPath).
+			Str("blob", entry.Blob).
+			Msg("Unable to read the content last applied, patching the file as a whole.")
+		return "", false
+	}
+
+	return string(content), true
+}
+
+// Record records base as the content applied to the file at commonPath, written with content
+func (s *State) Record(commonPath, base, content string) {
+	entry := StateEntry{Blob: BlobSHA(base)}
+
+	if base != content {
+		entry.Content = base
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.Files[stateKey(commonPath)] != entry {
+		s.Files[stateKey(commonPath)] = entry
+		s.changed = true
+	}
+}
+
+// Remove forgets the file at commonPath
+func (s *State) Remove(commonPath string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.Files[stateKey(commonPath)]; exists {
+		delete(s.Files, stateKey(commonPath))
+		s.changed = true
+	}
+}
+
+// Rename moves the record of the file at commonPath to newPath
+func (s *State) Rename(commonPath, newPath string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, exists := s.Files[stateKey(commonPath)]; exists {
+		delete(s.Files, stateKey(commonPath))
+		s.Files[stateKey(newPath)] = entry
+		s.changed = true
+	}
+}
+
+// Save writes the StateFile, only if the state changed since it was loaded
+func (s *State) Save() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.changed {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Unable to encode "+StateFile)
+	}
+
+	if err = writeFile(s.path, string(content)+"\n", 0); err != nil {
+		return err
+	}
+
+	s.changed = false
+
+	return nil
+}
+
+// BlobSHA returns the git blob sha of the content
+func BlobSHA(content string) string {
+	hash := sha1.New()
+	hash.Write([]byte("blob " + strconv.Itoa(len(content)) + "\x00" + content))
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func stateKey(commonPath string) string {
+	return strings.TrimPrefix(commonPath, "/")
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestBlobSHA(t *testing.T) {
+	// git hash-object of an empty file and of "a: 1\n"
+	for content, expected := range map[string]string{
+		"":       "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"a: 1\n": "a8926a52d8dcba5c4386945476ab6a2ad008afd4",
+	} {
+		if got := BlobSHA(content); got != expected {
+			t.Errorf("BlobSHA(%q) = %s, expected %s", content, got, expected)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuse-state")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	history := map[string][]byte{BlobSHA("a: 1\n"): []byte("a: 1\n")}
+	readBlob := func(sha string) ([]byte, error) {
+		if content, exists := history[sha]; exists {
+			return content, nil
+		}
+
+		return nil, errors.New("not found")
+	}
+
+	state, err := LoadState(dir, readBlob)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state.Record("/applied.yaml", "a: 1\n", "a: 1\n")
+	state.Record("/merged.yaml", "a: 1\n", "a: 1\nlocal: 1\n")
+	state.Record("/lost.yaml", "b: 1\n", "b: 1\n")
+	state.Record("/moved.yaml", "c: 1\n", "c: 1\n")
+	state.Rename("/moved.yaml", "/renamed.yaml")
+	state.Remove("/deleted.yaml")
+
+	if err = state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err = LoadState(dir, readBlob); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		original string
+		base     string
+		known    bool
+	}{
+		{"/applied.yaml", "a: 1\n", "a: 1\n", true},
+		{"/applied.yaml", "a: 1\nlocal: 1\n", "a: 1\n", true},
+		{"/merged.yaml", "a: 1\nlocal: 2\n", "a: 1\n", true},
+		{"/lost.yaml", "b: 2\n", "", false},
+		{"/renamed.yaml", "c: 1\n", "c: 1\n", true},
+		{"/moved.yaml", "c: 1\n", "", false},
+		{"/unknown.yaml", "d: 1\n", "", false},
+	}
+
+	for _, test := range tests {
+		if base, known := state.Base(test.path, test.original); base != test.base || known != test.known {
+			t.Errorf("Base(%s) = %q, %v, expected %q, %v", test.path, base, known, test.base, test.known)
+		}
+	}
+
+	// recording unchanged content leaves the state file untouched
+	if err = os.Remove(filepath.Join(dir, StateFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	state.Record("/applied.yaml", "a: 1\n", "a: 1\n")
+
+	if err = state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, StateFile)); !os.IsNotExist(err) {
+		t.Errorf("expected the unchanged state not to be written, got %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Strategy applies the update of a work item to its original destination
+type Strategy func(w *WorkItem) WorkItemResult
+
+// Strategy names, used by rules to choose how files are applied
+const (
+	// StrategyPatch patches the original with the update, merging the changes made since fuse last applied it
+	StrategyPatch = "patch"
+	// StrategyOverwrite replaces the original with the update
+	StrategyOverwrite = "overwrite"
+	// StrategyMerge deep merges yaml and json updates into the original document
+	StrategyMerge = "merge"
+	// StrategyAppend appends the update to the original, unless the original already contains it
+	StrategyAppend = "append"
+	// StrategyCreate creates the original with the update only if it doesn't exist
+	StrategyCreate = "create"
+	// StrategyDelete deletes the original, the update content is ignored
+	StrategyDelete = "delete"
+	// StrategyBlock replaces the region of the original between the fuse block markers with the update
+	StrategyBlock = "block"
+	// StrategyCopy copies the update verbatim, without marking it as managed by fuse. Binary files are copied, unless
+	// created, deleted or renamed.
+	StrategyCopy = "copy"
+	// StrategyRename moves the original to the path, relative to the repository root, held by the update
+	StrategyRename = "rename"
+)
+
+// Tombstones are content files named after the file they delete or rename, plus one of these suffixes
+const (
+	DeleteSuffix = ".fuse-delete"
+	RenameSuffix = ".fuse-rename"
+)
+
+var strategies = map[string]Strategy{
+	StrategyPatch:     (*WorkItem).ComputeDiffPatch,
+	StrategyOverwrite: (*WorkItem).overwrite,
+	StrategyMerge:     (*WorkItem).structuredMerge,
+	StrategyAppend:    (*WorkItem).appendUpdate,
+	StrategyCreate:    (*WorkItem).createMissing,
+	StrategyDelete:    (*WorkItem).delete,
+	StrategyBlock:     (*WorkItem).block,
+	StrategyRename:    (*WorkItem).rename,
+	StrategyCopy:      (*WorkItem).copy,
+}
+
+// textStrategies edit the file text, binary files are copied instead
+var textStrategies = map[string]bool{
+	StrategyPatch:     true,
+	StrategyMerge:     true,
+	StrategyBlock:     true,
+	StrategyAppend:    true,
+	StrategyOverwrite: true,
+}
+
+// structuredRules are the rules enabled by CrawlOptions.StructuredMerge
+var structuredRules = []StrategyRule{
+	{Glob: "*.yaml", Strategy: StrategyMerge},
+	{Glob: "*.yml", Strategy: StrategyMerge},
+	{Glob: "*.json", Strategy: StrategyMerge},
+}
+
+// StrategyRule selects the strategy of the files matching Glob. Globs are matched against the path relative to the
+// content directory, or against the file name when they have no /. * and ? don't match /, while ** matches any directories.
+type StrategyRule struct {
+	Glob     string
+	Strategy string
+}
+
+// ParseStrategyRules parses rules in the form glob=strategy, e.g: **/*.yaml=merge
+func ParseStrategyRules(values []string) ([]StrategyRule, error) {
+	rules := make([]StrategyRule, 0, len(values))
+
+	for _, value := range values {
+		i := strings.LastIndex(value, "=")
+
+		if i <= 0 {
+			return nil, errors.Errorf("invalid strategy rule %q, expected glob=strategy", value)
+		}
+
+		rule := StrategyRule{Glob: value[:i], Strategy: value[i+1:]}
+
+		if _, ok := strategies[rule.Strategy]; !ok {
+			return nil, errors.Errorf("unknown strategy %q in rule %q", rule.Strategy, value)
+		}
+
+		if _, err := globRegexp(rule.Glob); err != nil {
+			return nil, errors.Wrap(err, "Invalid glob "+rule.Glob)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// SelectStrategy returns the strategy of the first rule matching commonPath, or StrategyPatch if none does
+func SelectStrategy(rules []StrategyRule, commonPath string) string {
+	for _, rule := range rules {
+		if MatchGlob(rule.Glob, commonPath) {
+			return rule.Strategy
+		}
+	}
+
+	return StrategyPatch
+}
+
+// MatchGlob checks if the path, relative to the content directory, matches the glob
+func MatchGlob(glob, filePath string) bool {
+	filePath = strings.TrimPrefix(filePath, "/")
+	glob = strings.TrimPrefix(glob, "/")
+
+	if !strings.Contains(glob, "/") {
+		filePath = path.Base(filePath)
+	}
+
+	re, err := globRegexp(glob)
+
+	return err == nil && re.MatchString(filePath)
+}
+
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+
+	expr.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// Apply processes the work item with its strategy
+func (w *WorkItem) Apply() WorkItemResult {
+	if w.LinkTarget != "" {
+		return w.symlink()
+	}
+
+	name := w.Strategy
+
+	if name == "" {
+		name = StrategyPatch
+	}
+
+	strategy, ok := strategies[name]
+
+	if !ok {
+		return errorResult(w, errors.Errorf("unknown strategy %q", name))
+	}
+
+	result := strategy(w)
+
+	if result.Err != nil || result.Deleted || result.Renamed || w.Mode == 0 {
+		return result
+	}
+
+	return w.applyMode(result)
+}
+
+// applyMode sets the update permission bits on the result. Like git, only changes to the executable bit are diffs.
+func (w *WorkItem) applyMode(result WorkItemResult) WorkItemResult {
+	result.Mode = w.Mode
+
+	info, err := os.Stat(w.OriginalAbsPath)
+
+	if err != nil || result.HasDiffs || info.Mode().Perm()&0111 == w.Mode&0111 {
+		return result
+	}
+
+	result.HasDiffs = true
+	result.Diff = "old mode " + gitMode(info.Mode().Perm()) + "\nnew mode " + gitMode(w.Mode) + "\n"
+
+	return result
+}
+
+func gitMode(mode os.FileMode) string {
+	if mode&0111 != 0 {
+		return "100755"
+	}
+
+	return "100644"
+}
+
+// readOriginal reads the original content. It is nil when the original doesn't exist.
+func (w *WorkItem) readOriginal() (*string, error) {
+	originalBytes, err := ioutil.ReadFile(w.OriginalAbsPath)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	original := string(originalBytes)
+
+	return &original, nil
+}
+
+// result builds the result of applying content to the original, recording base as the content fuse applied
+func (w *WorkItem) result(original *string, content, base string) WorkItemResult {
+	result := WorkItemResult{
+		WorkItemID:      w.ID,
+		OriginalAbsPath: w.OriginalAbsPath,
+		UpdateAbsPath:   w.UpdateAbsPath,
+		CommonPath:      w.CommonPath,
+		ResultText:      content,
+		Diff:            UnifiedDiff(w.CommonPath, original, content),
+		HasDiffs:        original == nil || *original != content,
+		Created:         original == nil,
+	}
+
+	if base != "" {
+		result.State = w.State
+		result.BaseText = base
+	}
+
+	return result
+}
+
+func (w *WorkItem) overwrite() WorkItemResult {
+	updateContent, err := w.readUpdate()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	decoratedContent := decorateUpdateContent(updateContent, w.Comment)
+
+	return w.result(original, decoratedContent, decoratedContent)
+}
+
+// appendUpdate appends the update as is, there's no managed by fuse mark as fuse doesn't own the file
+func (w *WorkItem) appendUpdate() WorkItemResult {
+	updateContent, err := w.readUpdate()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	update := updateContent
+
+	if original == nil {
+		return w.result(nil, update, "")
+	}
+
+	if strings.Contains(*original, update) {
+		return w.result(original, *original, "")
+	}
+
+	content := *original
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	return w.result(original, content+update, "")
+}
+
+func (w *WorkItem) createMissing() WorkItemResult {
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	if original != nil {
+		return w.result(original, *original, "")
+	}
+
+	if w.Binary {
+		return w.copy()
+	}
+
+	return w.overwrite()
+}
+
+func (w *WorkItem) delete() WorkItemResult {
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	result := WorkItemResult{
+		WorkItemID:      w.ID,
+		OriginalAbsPath: w.OriginalAbsPath,
+		UpdateAbsPath:   w.UpdateAbsPath,
+		State:           w.State,
+		CommonPath:      w.CommonPath,
+		Deleted:         true,
+	}
+
+	if original != nil {
+		result.Diff = DeletedDiff(w.CommonPath, *original)
+		result.HasDiffs = true
+	}
+
+	return result
+}
+
+// renamePath reads the path, relative to the repository root, the rename tombstone moves the original to
+func (w *WorkItem) renamePath() (string, error) {
+	updateContent, err := w.readUpdate()
+
+	if err != nil {
+		return "", err
+	}
+
+	newPath := path.Clean("/" + strings.TrimSpace(updateContent))
+
+	if newPath == "/" || newPath == w.CommonPath {
+		return "", errors.Errorf("invalid rename of %s to %q", w.CommonPath, updateContent)
+	}
+
+	return newPath, nil
+}
+
+func (w *WorkItem) rename() WorkItemResult {
+	newPath, err := w.renamePath()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	root := strings.TrimSuffix(w.OriginalAbsPath, w.CommonPath)
+	result := WorkItemResult{
+		WorkItemID:      w.ID,
+		OriginalAbsPath: w.OriginalAbsPath,
+		UpdateAbsPath:   w.UpdateAbsPath,
+		State:           w.State,
+		RenamedAbsPath:  root + newPath,
+		RenamedPath:     newPath,
+		CommonPath:      w.CommonPath,
+		Renamed:         true,
+	}
+
+	_, err = os.Stat(result.RenamedAbsPath)
+
+	if err != nil && !os.IsNotExist(err) {
+		return errorResult(w, err)
+	}
+
+	renamedExists := err == nil
+
+	_, err = os.Stat(w.OriginalAbsPath)
+
+	switch {
+	case os.IsNotExist(err) && renamedExists:
+		// already renamed
+		return result
+	case err != nil:
+		return errorResult(w, err)
+	case renamedExists:
+		return errorResult(w, errors.Errorf("unable to rename %s, %s already exists", w.CommonPath, newPath))
+	}
+
+	result.Diff = "rename from " + strings.TrimPrefix(w.CommonPath, "/") + "\nrename to " + strings.TrimPrefix(newPath, "/") + "\n"
+	result.HasDiffs = true
+
+	return result
+}
+
+func (w *WorkItem) copy() WorkItemResult {
+	updateContent, err := ioutil.ReadFile(w.UpdateAbsPath)
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	original, err := w.readOriginal()
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	result := w.result(original, string(updateContent), "")
+
+	if w.Binary && result.HasDiffs {
+		result.Diff = BinaryDiff(w.CommonPath, original == nil)
+	}
+
+	return result
+}
+
+// symlink replaces the original with a symlink to the link target, unless it already is one
+func (w *WorkItem) symlink() WorkItemResult {
+	result := WorkItemResult{
+		WorkItemID:      w.ID,
+		OriginalAbsPath: w.OriginalAbsPath,
+		UpdateAbsPath:   w.UpdateAbsPath,
+		CommonPath:      w.CommonPath,
+		LinkTarget:      w.LinkTarget,
+	}
+
+	info, err := os.Lstat(w.OriginalAbsPath)
+
+	if os.IsNotExist(err) {
+		result.Diff = UnifiedDiff(w.CommonPath, nil, w.LinkTarget)
+		result.HasDiffs = true
+		result.Created = true
+
+		return result
+	}
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	// git stores symlinks as files holding their target
+	var original string
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		original, err = os.Readlink(w.OriginalAbsPath)
+	} else {
+		var content []byte
+		content, err = ioutil.ReadFile(w.OriginalAbsPath)
+		original = string(content)
+	}
+
+	if err != nil {
+		return errorResult(w, err)
+	}
+
+	result.HasDiffs = info.Mode()&os.ModeSymlink == 0 || original != w.LinkTarget
+
+	if result.HasDiffs {
+		result.Diff = UnifiedDiff(w.CommonPath, &original, w.LinkTarget)
+	}
+
+	return result
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		expected   bool
+	}{
+		{"*.yaml", "/values.yaml", true},
+		{"*.yaml", "/charts/app/values.yaml", true},
+		{"charts/*.yaml", "/charts/app/values.yaml", false},
+		{"charts/**/*.yaml", "/charts/app/values.yaml", true},
+		{"charts/**/*.yaml", "/charts/values.yaml", true},
+		{"/Makefile", "/Makefile", true},
+		{"?akefile", "/sub/Makefile", true},
+		{"*.yaml", "/values.yml", false},
+	}
+
+	for _, test := range tests {
+		if got := MatchGlob(test.glob, test.path); got != test.expected {
+			t.Errorf("MatchGlob(%q, %q) = %v", test.glob, test.path, got)
+		}
+	}
+}
+
+func TestSelectStrategy(t *testing.T) {
+	rules, err := ParseStrategyRules([]string{".gitignore=append", "**/*.yaml=merge", "*=overwrite"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"/.gitignore":   StrategyAppend,
+		"/a/b.yaml":     StrategyMerge,
+		"/README.md":    StrategyOverwrite,
+		"/docs/faq.txt": StrategyOverwrite,
+	} {
+		if got := SelectStrategy(rules, path); got != expected {
+			t.Errorf("expected %s for %s, got %s", expected, path, got)
+		}
+	}
+
+	if got := SelectStrategy(nil, "/a.txt"); got != StrategyPatch {
+		t.Errorf("expected the patch strategy by default, got %s", got)
+	}
+
+	if _, err := ParseStrategyRules([]string{"*.txt=replace"}); err == nil {
+		t.Error("expected unknown strategies to be rejected")
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuse-strategy")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	update := write("update", "dist/\n")
+
+	tests := []struct {
+		strategy, original, expected string
+	}{
+		{StrategyAppend, "bin/", "bin/\ndist/\n"},
+		{StrategyAppend, "dist/\nbin/\n", "dist/\nbin/\n"},
+		{StrategyOverwrite, "bin/\n", "# managed by fuse\ndist/\n"},
+		{StrategyCreate, "bin/\n", "bin/\n"},
+	}
+
+	for _, test := range tests {
+		w := WorkItem{
+			OriginalAbsPath: write("original", test.original),
+			UpdateAbsPath:   update,
+			CommonPath:      "/original",
+			Comment:         hashComment,
+			Strategy:        test.strategy,
+		}
+
+		result := w.Apply()
+
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+
+		if result.ResultText != test.expected || result.HasDiffs != (test.original != test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.strategy, test.expected, result.ResultText)
+		}
+	}
+
+	w := WorkItem{OriginalAbsPath: write("original", "bin/\n"), UpdateAbsPath: update, CommonPath: "/original",
+		Strategy: StrategyDelete}
+	result := w.Apply()
+
+	if err = result.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(w.OriginalAbsPath); !os.IsNotExist(err) || !result.HasDiffs {
+		t.Errorf("expected the original to be deleted, got %v", err)
+	}
+
+	if expected := "--- a/original\n+++ /dev/null\n@@ -1 +0,0 @@\n-bin/\n"; result.Diff != expected {
+		t.Errorf("unexpected deletion diff %q", result.Diff)
+	}
+}
+
+func TestStrategiesKeepFirstLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuse-strategy")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name, strategy, update, expected string
+	}{
+		{"deploy.sh", StrategyOverwrite, "#!/bin/sh\necho deploy\n", "#!/bin/sh\n# managed by fuse\necho deploy\n"},
+		{"deploy.sh", StrategyPatch, "#!/bin/sh\necho deploy\n", "#!/bin/sh\n# managed by fuse\necho deploy\n"},
+		{"run.py", StrategyOverwrite, "#!/usr/bin/env python3", "#!/usr/bin/env python3\n# managed by fuse\n"},
+		{"pom.xml", StrategyOverwrite, "<?xml version=\"1.0\"?>\n<project/>\n",
+			"<?xml version=\"1.0\"?>\n<!-- managed by fuse -->\n<project/>\n"},
+		{"app.csproj", StrategyPatch, "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<Project/>\n",
+			"<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<!-- managed by fuse -->\n<Project/>\n"},
+		{"index.html", StrategyOverwrite, "<html/>\n", "<!-- managed by fuse -->\n<html/>\n"},
+	}
+
+	for _, test := range tests {
+		update := filepath.Join(dir, "update")
+
+		if err = ioutil.WriteFile(update, []byte(test.update), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		w := WorkItem{
+			OriginalAbsPath: filepath.Join(dir, test.name),
+			UpdateAbsPath:   update,
+			CommonPath:      "/" + test.name,
+			Comment:         CommentStyleOf(test.name, nil, "//"),
+			Strategy:        test.strategy,
+		}
+
+		result := w.Apply()
+
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+
+		if result.ResultText != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.strategy, test.name, test.expected, result.ResultText)
+		}
+	}
+}
+
+func TestApplyMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuse-mode")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	original, update := filepath.Join(dir, "build.sh"), filepath.Join(dir, "update")
+
+	for _, path := range []string{original, update} {
+		if err = ioutil.WriteFile(path, []byte("echo build\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := WorkItem{OriginalAbsPath: original, UpdateAbsPath: update, CommonPath: "/build.sh", Strategy: StrategyCopy,
+		Mode: 0755}
+	result := w.Apply()
+
+	if !result.HasDiffs || result.Diff != "old mode 100644\nnew mode 100755\n" {
+		t.Errorf("expected a mode diff, got %+v", result)
+	}
+
+	if err = result.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := os.Stat(original); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("expected the original to be executable, got %v %v", info.Mode(), err)
+	}
+}
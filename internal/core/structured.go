@@ -0,0 +1,378 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// List merge strategies used by the structured merge
+const (
+	// ListReplace replaces the target list with the update list
+	ListReplace = "replace"
+	// ListAppend appends the update items missing from the target list
+	ListAppend = "append"
+	// ListMergeByKey deep merges list items that are maps with the same value at ListMerge.Key, appending the remaining ones
+	ListMergeByKey = "key"
+)
+
+// ListMerge defines how lists present both in the target and in the update documents are merged
+type ListMerge struct {
+	Strategy string
+	Key      string
+}
+
+// ParseListMerge parses a list merge strategy in the form replace, append or key=<field>. Empty defaults to replace.
+func ParseListMerge(value string) (ListMerge, error) {
+	switch {
+	case value == "" || value == ListReplace:
+		return ListMerge{Strategy: ListReplace}, nil
+	case value == ListAppend:
+		return ListMerge{Strategy: ListAppend}, nil
+	case strings.HasPrefix(value, ListMergeByKey+"=") && len(value) > len(ListMergeByKey)+1:
+		return ListMerge{Strategy: ListMergeByKey, Key: value[len(ListMergeByKey)+1:]}, nil
+	}
+
+	return ListMerge{}, errors.Errorf("invalid list merge %q, expected replace, append or key=<field>", value)
+}
+
+// StructuredMerge deep merges the update document into the target document. Keys only present in the target are kept, keys
+// present in both are merged recursively and the remaining update keys are appended, preserving the target key order.
+// The target is returned untouched when the merge doesn't change its content. Otherwise yaml documents are encoded again
+// keeping their comments, key order and scalar styles, while blank lines are dropped and lists are indented under their key.
+func StructuredMerge(filePath, target, update string, lists ListMerge) (string, error) {
+	targetDoc, err := decodeDocument(target)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to parse "+filePath)
+	}
+
+	updateDoc, err := decodeDocument(update)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to parse update of "+filePath)
+	}
+
+	if !mergeNode(targetDoc, updateDoc, lists) {
+		return target, nil
+	}
+
+	if path.Ext(filePath) == ".json" {
+		var out bytes.Buffer
+
+		if err = encodeJSON(&out, targetDoc, ""); err != nil {
+			return "", errors.Wrap(err, "Unable to encode "+filePath)
+		}
+
+		return out.String() + "\n", nil
+	}
+
+	var out bytes.Buffer
+
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(yamlIndent(target))
+
+	if err = encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{targetDoc}}); err != nil {
+		return "", errors.Wrap(err, "Unable to encode "+filePath)
+	}
+
+	if err = encoder.Close(); err != nil {
+		return "", errors.Wrap(err, "Unable to encode "+filePath)
+	}
+
+	return out.String(), nil
+}
+
+// decodeDocument decodes a single yaml mapping document, json being a subset of yaml, keeping its comments and styles.
+// Empty documents are empty mappings.
+func decodeDocument(content string) (*yaml.Node, error) {
+	var doc yaml.Node
+
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+
+	if err := decoder.Decode(&doc); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var next yaml.Node
+
+	if err := decoder.Decode(&next); err != io.EOF {
+		return nil, errors.New("multi document files are not supported")
+	}
+
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: doc.HeadComment}, nil
+	}
+
+	root := doc.Content[0]
+
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("the document is not a mapping")
+	}
+
+	// comments before the first key belong to the document
+	if doc.HeadComment != "" {
+		root.HeadComment = strings.TrimSpace(doc.HeadComment + "\n\n" + root.HeadComment)
+	}
+
+	return root, nil
+}
+
+// yamlIndent returns the indentation of the yaml content, the smallest one of its indented lines, 2 if there's none
+func yamlIndent(content string) int {
+	indent := 0
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+
+		if spaces > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && (indent == 0 || spaces < indent) {
+			indent = spaces
+		}
+	}
+
+	if indent < 2 || indent > 9 {
+		return 2
+	}
+
+	return indent
+}
+
+// mergeNode merges the update node into the target one, reporting if the target content changed
+func mergeNode(target, update *yaml.Node, lists ListMerge) bool {
+	if target.Kind == yaml.MappingNode && update.Kind == yaml.MappingNode {
+		return mergeMaps(target, update, lists)
+	}
+
+	if target.Kind == yaml.SequenceNode && update.Kind == yaml.SequenceNode {
+		return mergeLists(target, update, lists)
+	}
+
+	return replaceNode(target, update)
+}
+
+// replaceNode replaces the target node by the update one when their values differ, keeping the target comments
+func replaceNode(target, update *yaml.Node) bool {
+	if equalNodes(target, update) {
+		return false
+	}
+
+	head, line, foot := target.HeadComment, target.LineComment, target.FootComment
+	*target = *update
+
+	if target.HeadComment == "" && target.LineComment == "" && target.FootComment == "" {
+		target.HeadComment, target.LineComment, target.FootComment = head, line, foot
+	}
+
+	// flow style update values, e.g: json ones, don't turn block style target values into flow style
+	if target.Kind != yaml.ScalarNode {
+		target.Style &^= yaml.FlowStyle
+	}
+
+	return true
+}
+
+func mergeMaps(target, update *yaml.Node, lists ListMerge) bool {
+	changed := false
+
+	for i := 0; i+1 < len(update.Content); i += 2 {
+		key, value := update.Content[i], update.Content[i+1]
+
+		if j := indexOfKey(target, key); j >= 0 {
+			changed = mergeNode(target.Content[j+1], value, lists) || changed
+		} else {
+			target.Content = append(target.Content, key, value)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// mergeLists merges lists according to the list merge strategy. Items already present are not appended again, so
+// fusing the same content twice doesn't change the result.
+func mergeLists(target, update *yaml.Node, lists ListMerge) bool {
+	if lists.Strategy != ListAppend && lists.Strategy != ListMergeByKey {
+		return replaceNode(target, update)
+	}
+
+	changed := false
+
+	for _, item := range update.Content {
+		if lists.Strategy == ListMergeByKey {
+			if i := indexOfListKey(target, lists.Key, item); i >= 0 {
+				changed = mergeNode(target.Content[i], item, lists) || changed
+				continue
+			}
+		}
+
+		if !containsNode(target, item) {
+			target.Content = append(target.Content, item)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// indexOfKey returns the index of the key in the mapping node content, -1 if it's missing
+func indexOfKey(m *yaml.Node, key *yaml.Node) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if equalNodes(m.Content[i], key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// indexOfListKey finds the mapping in the list node with the same value at key as item
+func indexOfListKey(list *yaml.Node, key string, item *yaml.Node) int {
+	if item.Kind != yaml.MappingNode {
+		return -1
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	ki := indexOfKey(item, keyNode)
+
+	if ki < 0 {
+		return -1
+	}
+
+	for i, candidate := range list.Content {
+		if candidate.Kind != yaml.MappingNode {
+			continue
+		}
+
+		if ci := indexOfKey(candidate, keyNode); ci >= 0 && equalNodes(candidate.Content[ci+1], item.Content[ki+1]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func containsNode(list *yaml.Node, value *yaml.Node) bool {
+	for _, item := range list.Content {
+		if equalNodes(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// equalNodes compares the values of the nodes, regardless of their comments and styles
+func equalNodes(a, b *yaml.Node) bool {
+	var aValue, bValue interface{}
+
+	if a.Decode(&aValue) != nil || b.Decode(&bValue) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// encodeJSON writes the node as indented json, keeping the key order
+func encodeJSON(out *bytes.Buffer, node *yaml.Node, indent string) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return encodeJSON(out, node.Alias, indent)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			out.WriteString("{}")
+			return nil
+		}
+
+		out.WriteString("{\n")
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, err := marshalJSON(node.Content[i].Value)
+
+			if err != nil {
+				return err
+			}
+
+			out.WriteString(indent + "  ")
+			out.Write(key)
+			out.WriteString(": ")
+
+			if err = encodeJSON(out, node.Content[i+1], indent+"  "); err != nil {
+				return err
+			}
+
+			if i+2 < len(node.Content) {
+				out.WriteString(",")
+			}
+
+			out.WriteString("\n")
+		}
+
+		out.WriteString(indent + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			out.WriteString("[]")
+			return nil
+		}
+
+		out.WriteString("[\n")
+
+		for i, item := range node.Content {
+			out.WriteString(indent + "  ")
+
+			if err := encodeJSON(out, item, indent+"  "); err != nil {
+				return err
+			}
+
+			if i < len(node.Content)-1 {
+				out.WriteString(",")
+			}
+
+			out.WriteString("\n")
+		}
+
+		out.WriteString(indent + "]")
+	default:
+		// numbers keep their source literal, e.g: 1.0 would otherwise be written as 1
+		if (node.Tag == "!!int" || node.Tag == "!!float") && json.Valid([]byte(node.Value)) {
+			out.WriteString(node.Value)
+			return nil
+		}
+
+		var value interface{}
+
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+
+		encoded, err := marshalJSON(value)
+
+		if err != nil {
+			return err
+		}
+
+		out.Write(encoded)
+	}
+
+	return nil
+}
+
+// marshalJSON encodes the value as json without escaping <, > and &, which json.Marshal replaces for html safety
+func marshalJSON(value interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,103 @@
+package core
+
+import "testing"
+
+func TestStructuredMerge(t *testing.T) {
+	tests := []struct {
+		name, path, target, update, lists, expected string
+	}{
+		{
+			name:     "keeps target keys",
+			path:     "values.yaml",
+			target:   "image:\n  tag: v1\n  repository: app\nreplicas: 2\n",
+			update:   "image:\n  tag: v2\nenv: staging\n",
+			expected: "image:\n  tag: v2\n  repository: app\nreplicas: 2\nenv: staging\n",
+		},
+		{
+			name:     "unchanged keeps formatting",
+			path:     "values.yaml",
+			target:   "# comment\nreplicas:   2\n",
+			update:   "replicas: 2\n",
+			expected: "# comment\nreplicas:   2\n",
+		},
+		{
+			name:     "replaces lists",
+			path:     "values.yaml",
+			target:   "hosts:\n- a\n- b\n",
+			update:   "hosts:\n- c\n",
+			expected: "hosts:\n  - c\n",
+		},
+		{
+			name:     "appends missing list items",
+			path:     "values.yaml",
+			target:   "hosts:\n- a\n- b\n",
+			update:   "hosts:\n- b\n- c\n",
+			lists:    "append",
+			expected: "hosts:\n  - a\n  - b\n  - c\n",
+		},
+		{
+			name:     "merges list items by key",
+			path:     "values.yaml",
+			target:   "env:\n- name: A\n  value: \"1\"\n- name: B\n  value: \"2\"\n",
+			update:   "env:\n- name: B\n  value: \"3\"\n- name: C\n  value: \"4\"\n",
+			lists:    "key=name",
+			expected: "env:\n  - name: A\n    value: \"1\"\n  - name: B\n    value: \"3\"\n  - name: C\n    value: \"4\"\n",
+		},
+		{
+			name:     "keeps comments and styles",
+			path:     "values.yaml",
+			target:   "# app values\nimage:\n    # pinned by ci\n    tag: v1 # bumped by fuse\n    pullPolicy: 'Always'\nfeature:\n    enabled: on\n    mode: yes\n",
+			update:   "image:\n  tag: v2\nreplicas: 2\n",
+			expected: "# app values\nimage:\n    # pinned by ci\n    tag: v2 # bumped by fuse\n    pullPolicy: 'Always'\nfeature:\n    enabled: on\n    mode: yes\nreplicas: 2\n",
+		},
+		{
+			name:     "yaml 1.2 booleans",
+			path:     "values.yaml",
+			target:   "enabled: on\n",
+			update:   "enabled: \"on\"\n",
+			expected: "enabled: on\n",
+		},
+		{
+			name:     "json keeps key order",
+			path:     "config.json",
+			target:   "{\"b\": 1, \"a\": {\"x\": true}}",
+			update:   "{\"a\": {\"y\": \"z\"}, \"c\": []}",
+			expected: "{\n  \"b\": 1,\n  \"a\": {\n    \"x\": true,\n    \"y\": \"z\"\n  },\n  \"c\": []\n}\n",
+		},
+		{
+			name:     "json keeps html characters and number literals",
+			path:     "config.json",
+			target:   "{\"url\": \"<a&b>\", \"ratio\": 1.0}",
+			update:   "{\"scale\": 2.50, \"<key>\": 1e3}",
+			expected: "{\n  \"url\": \"<a&b>\",\n  \"ratio\": 1.0,\n  \"scale\": 2.50,\n  \"<key>\": 1e3\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lists, err := ParseListMerge(test.lists)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			merged, err := StructuredMerge(test.path, test.target, test.update, lists)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if merged != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, merged)
+			}
+		})
+	}
+}
+
+func TestParseListMerge(t *testing.T) {
+	for _, value := range []string{"key=", "merge", "key"} {
+		if _, err := ParseListMerge(value); err == nil {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// TemplateSuffix marks content files rendered as go templates before being applied. The suffix is dropped from the target path.
+const TemplateSuffix = ".fuse-tmpl"
+
+// TemplateData is the data available to templates, e.g: {{ .Repository }} or {{ .Vars.environment }}.
+// Owner is the user, organization, namespace or project the repository belongs to.
+type TemplateData struct {
+	Repository string
+	Owner      string
+	Vars       map[string]string
+}
+
+// readUpdate reads the update content, rendering it when the work item is a template
+func (w *WorkItem) readUpdate() (string, error) {
+	updateContent, err := ioutil.ReadFile(w.UpdateAbsPath)
+
+	if err != nil {
+		return "", err
+	}
+
+	if w.Template == nil {
+		return string(updateContent), nil
+	}
+
+	return RenderTemplate(path.Base(w.UpdateAbsPath), string(updateContent), *w.Template)
+}
+
+// RenderTemplate renders the template content with data. Referencing missing variables is an error.
+func RenderTemplate(name, content string, data TemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(content)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Template error")
+	}
+
+	var out bytes.Buffer
+
+	if err = tmpl.Execute(&out, data); err != nil {
+		return "", errors.Wrap(err, "Template error")
+	}
+
+	return out.String(), nil
+}
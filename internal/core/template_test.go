@@ -0,0 +1,21 @@
+package core
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	data := TemplateData{Repository: "service-a", Owner: "my-org", Vars: map[string]string{"region": "eun"}}
+
+	got, err := RenderTemplate("values.yaml", "name: {{ .Repository }}\nimage: {{ .Owner }}/{{ .Repository }}\nregion: {{ .Vars.region }}\n", data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "name: service-a\nimage: my-org/service-a\nregion: eun\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if _, err = RenderTemplate("values.yaml", "region: {{ .Vars.zone }}\n", data); err == nil {
+		t.Error("expected missing variables to be an error")
+	}
+}
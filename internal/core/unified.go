@@ -0,0 +1,164 @@
+// Package core contains the main functionality to crawl the directory and apply the appropriate patches to files
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change, like git and diff -u do
+const diffContextLines = 3
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// UnifiedDiff computes a line based unified diff, with file headers and hunks, between original and updated.
+// commonPath is the file path relative to the repository root. When original is nil the file is reported as created.
+// An empty string is returned when there are no differences.
+func UnifiedDiff(commonPath string, original *string, updated string) string {
+	originalText := ""
+	oldName := "a/" + strings.TrimPrefix(commonPath, "/")
+
+	if original == nil {
+		oldName = "/dev/null"
+	} else {
+		originalText = *original
+	}
+
+	lines := diffLines(originalText, updated)
+
+	var hunks strings.Builder
+	oldLine, newLine := 1, 1
+
+	for start := 0; start < len(lines); {
+		// find the next change
+		first := start
+		for first < len(lines) && lines[first].op == diffmatchpatch.DiffEqual {
+			first++
+		}
+
+		if first == len(lines) {
+			break
+		}
+
+		// skip the equal lines that are not part of the hunk context
+		skipped := first - diffContextLines
+		if skipped < start {
+			skipped = start
+		}
+
+		oldLine += skipped - start
+		newLine += skipped - start
+
+		// extend the hunk until there's more than twice the context of unchanged lines
+		end, equals := first, 0
+		for end < len(lines) && equals <= 2*diffContextLines {
+			if lines[end].op == diffmatchpatch.DiffEqual {
+				equals++
+			} else {
+				equals = 0
+			}
+			end++
+		}
+
+		end -= equals
+		if equals > 0 {
+			trailing := equals
+			if trailing > diffContextLines {
+				trailing = diffContextLines
+			}
+			end += trailing
+		}
+
+		oldCount, newCount := 0, 0
+		var body strings.Builder
+
+		for _, l := range lines[skipped:end] {
+			switch l.op {
+			case diffmatchpatch.DiffEqual:
+				body.WriteString(" ")
+				oldCount++
+				newCount++
+			case diffmatchpatch.DiffDelete:
+				body.WriteString("-")
+				oldCount++
+			case diffmatchpatch.DiffInsert:
+				body.WriteString("+")
+				newCount++
+			}
+
+			body.WriteString(l.text)
+
+			if !strings.HasSuffix(l.text, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		hunks.WriteString("@@ -" + hunkRange(oldLine, oldCount) + " +" + hunkRange(newLine, newCount) + " @@\n")
+		hunks.WriteString(body.String())
+
+		oldLine += oldCount
+		newLine += newCount
+		start = end
+	}
+
+	if hunks.Len() == 0 {
+		return ""
+	}
+
+	return "--- " + oldName + "\n+++ b/" + strings.TrimPrefix(commonPath, "/") + "\n" + hunks.String()
+}
+
+// BinaryDiff reports changes to binary files the way git does, without their content
+func BinaryDiff(commonPath string, created bool) string {
+	oldName := "a/" + strings.TrimPrefix(commonPath, "/")
+
+	if created {
+		oldName = "/dev/null"
+	}
+
+	return "Binary files " + oldName + " and b/" + strings.TrimPrefix(commonPath, "/") + " differ\n"
+}
+
+// DeletedDiff computes the unified diff of removing the file at commonPath
+func DeletedDiff(commonPath, original string) string {
+	diff := UnifiedDiff(commonPath, &original, "")
+
+	return strings.Replace(diff, "+++ b/"+strings.TrimPrefix(commonPath, "/")+"\n", "+++ /dev/null\n", 1)
+}
+
+// diffLines computes a line level diff and flattens it to one entry per line
+func diffLines(original, updated string) []diffLine {
+	dmp := diffmatchpatch.New()
+	originalChars, updatedChars, lineArray := dmp.DiffLinesToChars(original, updated)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(originalChars, updatedChars, false), lineArray)
+
+	var lines []diffLine
+
+	for _, diff := range diffs {
+		for _, text := range strings.SplitAfter(diff.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{op: diff.Type, text: text})
+			}
+		}
+	}
+
+	return lines
+}
+
+// hunkRange formats a hunk range, e.g: 3,4. Empty ranges point to the line before them, as expected by patch.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
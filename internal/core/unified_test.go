@@ -0,0 +1,44 @@
+package core
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	original := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	updated := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13"
+
+	expected := `--- a/dir/file.txt
++++ b/dir/file.txt
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+\ No newline at end of file
+`
+
+	if diff := UnifiedDiff("/dir/file.txt", &original, updated); diff != expected {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffCreatedAndUnchanged(t *testing.T) {
+	expected := "--- /dev/null\n+++ b/file.txt\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+
+	if diff := UnifiedDiff("/file.txt", nil, "a\nb\n"); diff != expected {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	original := "a\n"
+
+	if diff := UnifiedDiff("/file.txt", &original, original); diff != "" {
+		t.Errorf("expected no diff, got:\n%s", diff)
+	}
+}
@@ -1,14 +1,34 @@
 // Package domain contains domain types
 package domain
 
-// CommonInput are cli inputs that are common to all providers
+// CommonInput are cli inputs that are common to all providers.
+// Owner is the user, organization, namespace or project the repository belongs to and Vars the template variables.
+// GitClient names the git implementation used, empty for the provider default. Shallow and Sparse limit the clone to the last
+// commit and to the paths of the content directory. SSHKey, when set, clones and pushes over ssh with that private key, checking
+// host keys against the KnownHosts file, while Pat is still used for the provider api.
 type CommonInput struct {
 	RepositoryName   string
+	Owner            string
+	BaseBranch       string
 	Tag              string
 	Pat              string
 	ContentDir       string
 	CommentDelimiter string
+	CommentStyles    []string
 	Concurrency      int8
+	DryRun           bool
+	StructuredMerge  bool
+	ListMerge        string
+	Strategies       []string
+	Vars             map[string]string
+	Include          []string
+	Exclude          []string
+	Symlinks         string
+	GitClient        string
+	Shallow          bool
+	Sparse           bool
+	SSHKey           string
+	KnownHosts       string
 }
 
 // PullRequestInput are cli inputs related to pull requests
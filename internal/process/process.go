@@ -3,6 +3,7 @@ package process
 
 import (
 	"io/ioutil"
+	"os"
 	"os/exec"
 
 	"github.com/pkg/errors"
@@ -10,13 +11,18 @@ import (
 
 // ExecuteProcess will run a bash command through os.Exec.
 // If no workingDir is provided, its nil or empty, it will run in calling process's current directory.
-func ExecuteProcess(command string, workingDir *string) (stdoutStr, stderrStr string, err error) {
+// env, in the form key=value, is added to the calling process's environment, e.g: to pass secrets out of the command line.
+func ExecuteProcess(command string, workingDir *string, env ...string) (stdoutStr, stderrStr string, err error) {
 	cmd := exec.Command("/bin/sh", "-c", command)
 
 	if workingDir != nil && *workingDir != "" {
 		cmd.Dir = *workingDir
 	}
 
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
 	stdout, err := cmd.StdoutPipe()
 
 	if err != nil {
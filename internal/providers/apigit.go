@@ -0,0 +1,277 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// APIProvider is a provider able to read files and commit through its hosting api, without cloning the repository
+type APIProvider interface {
+	Provider
+	// BranchSHA returns the commit sha the branch points to
+	BranchSHA(branch string) (string, error)
+	// ReadFiles reads the files at the given paths, relative to the repository root, as of the commit sha.
+	// Paths that don't exist are left out of the result.
+	ReadFiles(commitSHA string, paths []string) (map[string]RemoteFile, error)
+	// PushFiles commits the changes on top of the parent commit and points the branch to it, creating it when newBranch is
+	// true. It returns the commit sha.
+	PushFiles(branch, parentSHA string, newBranch bool, changes []FileChange, message string) (string, error)
+	// ReadBlob reads the content of the blob sha
+	ReadBlob(sha string) ([]byte, error)
+	// CreateTag creates an annotated tag of the commit sha
+	CreateTag(tag, commitSHA, message string) error
+}
+
+// RemoteFile is the content and permission bits, 0644 or 0755, of a file read through a hosting api.
+// ModeUnknown is true when the api doesn't expose permission bits, the file is then only compared by content.
+type RemoteFile struct {
+	Content     []byte
+	Mode        os.FileMode
+	ModeUnknown bool
+}
+
+// FileChange is a file written, or deleted, by a commit created through a hosting api. Created is true when the file
+// didn't exist in the parent commit.
+type FileChange struct {
+	Path    string
+	Content []byte
+	Mode    os.FileMode
+	Created bool
+	Deleted bool
+}
+
+// APIGit is the GitClient of API providers. Instead of cloning, it writes the files at the sparse paths to a temporary
+// directory and commits the changes made to them through the hosting api. Commits are pushed as they're created and
+// symlinks are not supported.
+type APIGit struct {
+	provider  APIProvider
+	files     map[string]RemoteFile
+	branch    string
+	newBranch bool
+	parentSHA string
+	headSHA   string
+}
+
+// NewAPIGit returns the api git client of the provider
+func NewAPIGit(provider APIProvider) *APIGit {
+	return &APIGit{provider: provider}
+}
+
+// Clone reads the files at the sparse paths of the branch, writing them to a temporary directory the caller is responsible
+// to clean. The branch must be provided, the repository url and token are not used.
+func (g *APIGit) Clone(repositoryURL, repositoryName, token, branch string, options CloneOptions) (destination, gitCloneRoot string, err error) {
+	if branch == "" {
+		return "", "", errors.New("Git error: the api git client requires the base branch")
+	}
+
+	log.Info().
+		Str("repository", repositoryURL).
+		Int("paths", len(options.SparsePaths)).
+		Msg("Reading repository files through the api.")
+
+	g.branch = branch
+	g.parentSHA, err = g.provider.BranchSHA(branch)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	g.files, err = g.provider.ReadFiles(g.parentSHA, options.SparsePaths)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	destination, err = ioutil.TempDir("/tmp", "tmp")
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "Git error")
+	}
+
+	gitCloneRoot = filepath.Join(destination, repositoryName)
+
+	if err = os.MkdirAll(gitCloneRoot, os.ModePerm); err != nil {
+		return destination, "", errors.Wrap(err, "Git error")
+	}
+
+	for path, file := range g.files {
+		filePath := filepath.Join(gitCloneRoot, path)
+
+		if err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return destination, "", errors.Wrap(err, "Git error")
+		}
+
+		if err = ioutil.WriteFile(filePath, file.Content, file.Mode); err != nil {
+			return destination, "", errors.Wrap(err, "Git error")
+		}
+	}
+
+	log.Info().
+		Int("files", len(g.files)).
+		Msg("Successfully read to " + destination)
+
+	return destination, gitCloneRoot, nil
+}
+
+// Configure is a no-op, commits are authored by the owner of the token
+func (g *APIGit) Configure(repositoryDir string) error {
+	return nil
+}
+
+// CurrentBranch returns the branch the files were read from, or the one created since
+func (g *APIGit) CurrentBranch(repositoryDir string) (string, error) {
+	return g.branch, nil
+}
+
+// CreateBranch starts a branch from the commit the files were read from, created along with the first commit
+func (g *APIGit) CreateBranch(repositoryDir, branchName string) error {
+	g.branch = branchName
+	g.newBranch = true
+
+	return nil
+}
+
+// Commit commits the changes made to the read files, and the files created next to them, pushing them to the branch
+func (g *APIGit) Commit(repositoryDir string) error {
+	log.Info().
+		Msg("Committing changes through the api.")
+
+	changes, err := g.changes(repositoryDir)
+
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		return errors.New("Git error: nothing to commit")
+	}
+
+	sha, err := g.provider.PushFiles(g.branch, g.parentSHA, g.newBranch, changes, "Fuse automation")
+
+	if err != nil {
+		return err
+	}
+
+	g.headSHA, g.parentSHA, g.newBranch = sha, sha, false
+
+	log.Info().
+		Int("files", len(changes)).
+		Msg("Successfully committed changes.")
+
+	return nil
+}
+
+// ModesKnown tells if the permission bits of every read file are known, otherwise mode changes can't be told apart
+func (g *APIGit) ModesKnown() bool {
+	for _, file := range g.files {
+		if file.ModeUnknown {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HeadSHA returns the sha of the last commit
+func (g *APIGit) HeadSHA(repositoryDir string) (string, error) {
+	if g.headSHA == "" {
+		return "", errors.New("Git error: nothing was committed")
+	}
+
+	return g.headSHA, nil
+}
+
+// ReadBlob reads a blob of the repository history through the api
+func (g *APIGit) ReadBlob(repositoryDir, sha string) ([]byte, error) {
+	return g.provider.ReadBlob(sha)
+}
+
+// Tag creates an annotated tag of the last commit
+func (g *APIGit) Tag(repositoryDir, tag string) error {
+	log.Info().
+		Str("tag", tag).
+		Msg("Tagging commit through the api")
+
+	sha, err := g.HeadSHA(repositoryDir)
+
+	if err != nil {
+		return err
+	}
+
+	return g.provider.CreateTag(tag, sha, "Fuse release "+tag)
+}
+
+// Push is a no-op, commits are pushed as they're created
+func (g *APIGit) Push(repositoryDir, branch string) error {
+	return nil
+}
+
+// changes compares the files in the repository directory with the read ones, sorted by path
+func (g *APIGit) changes(repositoryDir string) ([]FileChange, error) {
+	var changes []FileChange
+	found := map[string]bool{}
+
+	err := filepath.Walk(repositoryDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(repositoryDir, path)
+
+		if err != nil {
+			return err
+		}
+
+		relPath = filepath.ToSlash(relPath)
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return errors.Errorf("%s is a symlink, not supported by the api git client", relPath)
+		}
+
+		content, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		mode := os.FileMode(0644)
+
+		if info.Mode()&0100 != 0 {
+			mode = 0755
+		}
+
+		original, exists := g.files[relPath]
+		found[relPath] = true
+
+		modeChanged := !original.ModeUnknown && original.Mode != mode
+
+		if !exists || !bytes.Equal(original.Content, content) || modeChanged {
+			changes = append(changes, FileChange{Path: relPath, Content: content, Mode: mode, Created: !exists})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	for path, original := range g.files {
+		if !found[path] {
+			changes = append(changes, FileChange{Path: path, Mode: original.Mode, Deleted: true})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
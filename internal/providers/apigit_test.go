@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"fuse/internal/domain"
+
+	"github.com/pkg/errors"
+)
+
+// fakeAPI is an in memory APIProvider recording the pushed changes and tags
+type fakeAPI struct {
+	GitRemote
+	files  map[string]RemoteFile
+	pushes []fakePush
+	tags   map[string]string
+}
+
+type fakePush struct {
+	branch    string
+	parentSHA string
+	newBranch bool
+	changes   []FileChange
+}
+
+func (f *fakeAPI) BranchSHA(branch string) (string, error) {
+	return "base", nil
+}
+
+func (f *fakeAPI) ReadFiles(commitSHA string, paths []string) (map[string]RemoteFile, error) {
+	files := map[string]RemoteFile{}
+
+	for _, path := range paths {
+		if file, exists := f.files[path]; exists {
+			files[path] = file
+		}
+	}
+
+	return files, nil
+}
+
+func (f *fakeAPI) PushFiles(branch, parentSHA string, newBranch bool, changes []FileChange, message string) (string, error) {
+	f.pushes = append(f.pushes, fakePush{branch: branch, parentSHA: parentSHA, newBranch: newBranch, changes: changes})
+
+	return "commit" + strconv.Itoa(len(f.pushes)), nil
+}
+
+func (f *fakeAPI) ReadBlob(sha string) ([]byte, error) {
+	return nil, errors.New("no history")
+}
+
+func (f *fakeAPI) CreateTag(tag, commitSHA, message string) error {
+	f.tags[tag] = commitSHA
+
+	return nil
+}
+
+func TestAPIGit(t *testing.T) {
+	api := &fakeAPI{
+		GitRemote: GitRemote{Common: domain.CommonInput{GitClient: GitClientAPI}},
+		files: map[string]RemoteFile{
+			"config/app.yaml": {Content: []byte("a: 1\n"), Mode: 0644},
+			"run.sh":          {Content: []byte("echo\n"), Mode: 0644},
+			"legacy.txt":      {Content: []byte("old\n"), Mode: 0644},
+			"other.txt":       {Content: []byte("untouched\n"), Mode: 0644},
+		},
+		tags: map[string]string{},
+	}
+
+	client := NewAPIGit(api)
+	destination, root, err := client.Clone("https://example.com/repo", "repo", "token", "main",
+		CloneOptions{SparsePaths: []string{"config/app.yaml", "run.sh", "legacy.txt", "new.txt"}})
+
+	if destination != "" {
+		defer os.RemoveAll(destination)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(filepath.Join(root, "other.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected only the sparse paths to be read, got %v", err)
+	}
+
+	if err = client.CreateBranch(root, "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(root, "config/app.yaml"), []byte("a: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chmod(filepath.Join(root, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Remove(filepath.Join(root, "legacy.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(root, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Commit(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Tag(root, "v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.pushes) != 1 {
+		t.Fatalf("expected a single push, got %+v", api.pushes)
+	}
+
+	push := api.pushes[0]
+
+	if push.branch != "feature" || push.parentSHA != "base" || !push.newBranch {
+		t.Errorf("unexpected push %+v", push)
+	}
+
+	expected := []FileChange{
+		{Path: "config/app.yaml", Content: []byte("a: 2\n"), Mode: 0644},
+		{Path: "legacy.txt", Mode: 0644, Deleted: true},
+		{Path: "new.txt", Content: []byte("new\n"), Mode: 0644, Created: true},
+		{Path: "run.sh", Content: []byte("echo\n"), Mode: 0755},
+	}
+
+	if len(push.changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), push.changes)
+	}
+
+	for i, change := range push.changes {
+		e := expected[i]
+
+		if change.Path != e.Path || string(change.Content) != string(e.Content) || change.Mode != e.Mode ||
+			change.Created != e.Created || change.Deleted != e.Deleted {
+			t.Errorf("expected change %+v, got %+v", e, change)
+		}
+	}
+
+	if sha, _ := client.HeadSHA(root); sha != "commit1" || api.tags["v1"] != "commit1" {
+		t.Errorf("expected commit1 to be tagged, got %s %v", sha, api.tags)
+	}
+}
+
+func TestAPIGitUnknownModes(t *testing.T) {
+	api := &fakeAPI{
+		GitRemote: GitRemote{Common: domain.CommonInput{GitClient: GitClientAPI}},
+		files: map[string]RemoteFile{
+			"run.sh": {Content: []byte("echo\n"), Mode: 0644, ModeUnknown: true},
+		},
+		tags: map[string]string{},
+	}
+
+	client := NewAPIGit(api)
+	destination, root, err := client.Clone("https://example.com/repo", "repo", "token", "main",
+		CloneOptions{SparsePaths: []string{"run.sh"}})
+
+	if destination != "" {
+		defer os.RemoveAll(destination)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.ModesKnown() {
+		t.Error("expected the modes not to be known")
+	}
+
+	if err = os.Chmod(filepath.Join(root, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes, err := client.changes(root); err != nil || len(changes) != 0 {
+		t.Errorf("expected unknown modes not to be changes, got %+v %v", changes, err)
+	}
+}
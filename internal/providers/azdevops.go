@@ -3,7 +3,11 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"fuse/internal/domain"
 
@@ -49,17 +53,32 @@ func (az *AzureDevOps) GetRepository() (*ProviderRepository, error) {
 		return nil, errors.WithStack(errors.Wrap(err, "AzureDevOps error"))
 	}
 
+	// the default branch is a full ref name, e.g: refs/heads/main. Empty repositories have none.
+	defaultBranch := ""
+
+	if gitRepo.DefaultBranch != nil {
+		defaultBranch = strings.TrimPrefix(*gitRepo.DefaultBranch, "refs/heads/")
+	}
+
+	sshURL := ""
+
+	if gitRepo.SshUrl != nil {
+		sshURL = *gitRepo.SshUrl
+	}
+
 	return &ProviderRepository{
-		WebURL: *gitRepo.WebUrl,
-		Name:   *gitRepo.Name,
+		WebURL:        *gitRepo.WebUrl,
+		SSHURL:        sshURL,
+		Name:          *gitRepo.Name,
+		DefaultBranch: defaultBranch,
 	}, nil
 }
 
 // CreatePullRequest creates a pull request on azure devops
-func (az *AzureDevOps) CreatePullRequest(sourceBranch *string) (*ProviderPullRequest, error) {
+func (az *AzureDevOps) CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error) {
 	log.Info().
 		Str("prTitle", az.PullRequest.Title).
-		Str("prTarget", TargetBranch).
+		Str("prTarget", *targetBranch).
 		Str("prSource", *sourceBranch).
 		Str("repoName", az.Common.RepositoryName).
 		Msg("Creating GitHub pull request")
@@ -76,7 +95,7 @@ func (az *AzureDevOps) CreatePullRequest(sourceBranch *string) (*ProviderPullReq
 	}
 
 	refPrefix := "refs/heads/"
-	prTarget := refPrefix + TargetBranch
+	prTarget := refPrefix + *targetBranch
 	prSource := refPrefix + *sourceBranch
 
 	prMetadata := git.GitPullRequest{
@@ -122,6 +141,223 @@ func (az *AzureDevOps) CreatePullRequest(sourceBranch *string) (*ProviderPullReq
 	}, nil
 }
 
+// BranchSHA returns the commit sha the branch points to
+func (az *AzureDevOps) BranchSHA(branch string) (string, error) {
+	ctx := context.Background()
+	gitClient, err := git.NewClient(ctx, azuredevops.NewPatConnection(az.OrganizationURL, az.Common.Pat))
+
+	if err != nil {
+		return "", errors.Wrap(err, "AzureDevOps error")
+	}
+
+	filter := "heads/" + branch
+	refs, err := gitClient.GetRefs(ctx, git.GetRefsArgs{
+		RepositoryId: &az.Common.RepositoryName,
+		Project:      &az.ProjectName,
+		Filter:       &filter,
+	})
+
+	if err != nil {
+		return "", errors.Wrap(err, "AzureDevOps error")
+	}
+
+	// the filter is a prefix, e.g: heads/main also matches heads/main-old
+	for _, ref := range refs.Value {
+		if ref.Name != nil && *ref.Name == "refs/heads/"+branch && ref.ObjectId != nil {
+			return *ref.ObjectId, nil
+		}
+	}
+
+	return "", errors.Errorf("AzureDevOps error: branch %s not found", branch)
+}
+
+// ReadFiles reads the files at the given paths as of the commit sha through the items api. Paths that don't exist are left out.
+// The api doesn't expose permission bits, files are read as 0644.
+func (az *AzureDevOps) ReadFiles(commitSHA string, paths []string) (map[string]RemoteFile, error) {
+	ctx := context.Background()
+	gitClient, err := git.NewClient(ctx, azuredevops.NewPatConnection(az.OrganizationURL, az.Common.Pat))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "AzureDevOps error")
+	}
+
+	files := map[string]RemoteFile{}
+
+	for _, filePath := range paths {
+		itemPath := "/" + filePath
+		reader, err := gitClient.GetItemContent(ctx, git.GetItemContentArgs{
+			RepositoryId: &az.Common.RepositoryName,
+			Project:      &az.ProjectName,
+			Path:         &itemPath,
+			VersionDescriptor: &git.GitVersionDescriptor{
+				Version:     &commitSHA,
+				VersionType: &git.GitVersionTypeValues.Commit,
+			},
+		})
+
+		if azureStatusCode(err) == http.StatusNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "AzureDevOps error")
+		}
+
+		content, err := ioutil.ReadAll(reader)
+		reader.Close()
+
+		if err != nil {
+			return nil, errors.Wrap(err, "AzureDevOps error")
+		}
+
+		files[filePath] = RemoteFile{Content: content, Mode: 0644, ModeUnknown: true}
+	}
+
+	return files, nil
+}
+
+// ReadBlob reads the content of the blob sha through the blobs api
+func (az *AzureDevOps) ReadBlob(sha string) ([]byte, error) {
+	ctx := context.Background()
+	gitClient, err := git.NewClient(ctx, azuredevops.NewPatConnection(az.OrganizationURL, az.Common.Pat))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "AzureDevOps error")
+	}
+
+	reader, err := gitClient.GetBlobContent(ctx, git.GetBlobContentArgs{
+		RepositoryId: &az.Common.RepositoryName,
+		Project:      &az.ProjectName,
+		Sha1:         &sha,
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "AzureDevOps error")
+	}
+
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "AzureDevOps error")
+	}
+
+	return content, nil
+}
+
+// PushFiles creates a push with a commit of the changes on top of the parent commit, creating or updating the branch.
+// The api doesn't support permission bits, new files are created as 0644 and existing ones keep theirs.
+func (az *AzureDevOps) PushFiles(branch, parentSHA string, newBranch bool, changes []FileChange, message string) (string, error) {
+	log.Info().
+		Str("branch", branch).
+		Int("files", len(changes)).
+		Msg("Creating AzureDevOps push")
+
+	ctx := context.Background()
+	gitClient, err := git.NewClient(ctx, azuredevops.NewPatConnection(az.OrganizationURL, az.Common.Pat))
+
+	if err != nil {
+		return "", errors.Wrap(err, "AzureDevOps error")
+	}
+
+	var commitChanges []interface{}
+
+	for _, change := range changes {
+		itemPath := "/" + change.Path
+		commitChange := git.Change{
+			ChangeType: &git.VersionControlChangeTypeValues.Edit,
+			Item:       git.GitItem{Path: &itemPath},
+		}
+
+		switch {
+		case change.Deleted:
+			commitChange.ChangeType = &git.VersionControlChangeTypeValues.Delete
+		case change.Created:
+			commitChange.ChangeType = &git.VersionControlChangeTypeValues.Add
+		}
+
+		if !change.Deleted {
+			content := base64.StdEncoding.EncodeToString(change.Content)
+			commitChange.NewContent = &git.ItemContent{
+				Content:     &content,
+				ContentType: &git.ItemContentTypeValues.Base64Encoded,
+			}
+		}
+
+		commitChanges = append(commitChanges, commitChange)
+	}
+
+	// new branches are created from the parent commit, given as their old object id
+	refName := "refs/heads/" + branch
+	push, err := gitClient.CreatePush(ctx, git.CreatePushArgs{
+		RepositoryId: &az.Common.RepositoryName,
+		Project:      &az.ProjectName,
+		Push: &git.GitPush{
+			RefUpdates: &[]git.GitRefUpdate{{
+				Name:        &refName,
+				OldObjectId: &parentSHA,
+			}},
+			Commits: &[]git.GitCommitRef{{
+				Comment: &message,
+				Changes: &commitChanges,
+			}},
+		},
+	})
+
+	if err != nil {
+		return "", errors.Wrap(err, "AzureDevOps error")
+	}
+
+	if push.Commits == nil || len(*push.Commits) == 0 || (*push.Commits)[0].CommitId == nil {
+		return "", errors.New("AzureDevOps error: the push has no commit")
+	}
+
+	return *(*push.Commits)[0].CommitId, nil
+}
+
+// CreateTag creates an annotated tag of the commit sha
+func (az *AzureDevOps) CreateTag(tag, commitSHA, message string) error {
+	ctx := context.Background()
+	gitClient, err := git.NewClient(ctx, azuredevops.NewPatConnection(az.OrganizationURL, az.Common.Pat))
+
+	if err != nil {
+		return errors.Wrap(err, "AzureDevOps error")
+	}
+
+	_, err = gitClient.CreateAnnotatedTag(ctx, git.CreateAnnotatedTagArgs{
+		RepositoryId: &az.Common.RepositoryName,
+		Project:      &az.ProjectName,
+		TagObject: &git.GitAnnotatedTag{
+			Name:         &tag,
+			Message:      &message,
+			TaggedObject: &git.GitObject{ObjectId: &commitSHA},
+		},
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "AzureDevOps error")
+	}
+
+	return nil
+}
+
+// azureStatusCode returns the http status code of an azure devops error, 0 if there's none
+func azureStatusCode(err error) int {
+	switch wrapped := err.(type) {
+	case azuredevops.WrappedError:
+		if wrapped.StatusCode != nil {
+			return *wrapped.StatusCode
+		}
+	case *azuredevops.WrappedError:
+		if wrapped.StatusCode != nil {
+			return *wrapped.StatusCode
+		}
+	}
+
+	return 0
+}
+
 // GetCommonInput returns common inputs provided by the user via cli
 func (az *AzureDevOps) GetCommonInput() *domain.CommonInput {
 	return &az.Common
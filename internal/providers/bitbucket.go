@@ -0,0 +1,280 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"fuse/internal/domain"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// BitbucketCloudURL is the bitbucket cloud api url, used when no bitbucket server base url is provided
+const BitbucketCloudURL = "https://api.bitbucket.org"
+
+// Bitbucket encapsulates bitbucket metadata and bridges communication to bitbucket server or bitbucket cloud.
+// When BaseURL is empty or points to bitbucket.org or its api, bitbucket cloud is used. Otherwise BaseURL should point to the
+// bitbucket server instance, e.g: https://bitbucket.mycompany.io
+// Project is the project key on bitbucket server or the workspace on bitbucket cloud.
+// Username is optional. If provided, basic authentication is used with the pat, otherwise the pat is used as a bearer token.
+type Bitbucket struct {
+	BaseURL     string
+	Project     string
+	Username    string
+	Common      domain.CommonInput
+	PullRequest domain.PullRequestInput
+}
+
+type bitbucketLink struct {
+	Href string `json:"href"`
+	Name string `json:"name"`
+}
+
+type bitbucketServerRepository struct {
+	Slug  string `json:"slug"`
+	Links struct {
+		Clone []bitbucketLink `json:"clone"`
+		Self  []bitbucketLink `json:"self"`
+	} `json:"links"`
+}
+
+type bitbucketServerPullRequest struct {
+	ID    int `json:"id"`
+	Links struct {
+		Self []bitbucketLink `json:"self"`
+	} `json:"links"`
+}
+
+type bitbucketServerBranch struct {
+	DisplayID string `json:"displayId"`
+}
+
+type bitbucketCloudRepository struct {
+	Slug       string `json:"slug"`
+	MainBranch struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+	Links struct {
+		Clone []bitbucketLink `json:"clone"`
+		HTML  bitbucketLink   `json:"html"`
+	} `json:"links"`
+}
+
+type bitbucketCloudPullRequest struct {
+	ID    int `json:"id"`
+	Links struct {
+		HTML bitbucketLink `json:"html"`
+	} `json:"links"`
+}
+
+// GetRepository will fetch the repository details on bitbucket
+func (bb *Bitbucket) GetRepository() (*ProviderRepository, error) {
+	log.Info().
+		Str("baseUrl", bb.baseURL()).
+		Str("project", bb.Project).
+		Str("repoName", bb.Common.RepositoryName).
+		Msg("Getting bitbucket repository")
+
+	if bb.isCloud() {
+		repository := bitbucketCloudRepository{}
+
+		if err := bb.client().do(http.MethodGet, bb.repositoryPath(), nil, &repository); err != nil {
+			return nil, errors.WithStack(errors.Wrap(err, "Bitbucket error"))
+		}
+
+		return &ProviderRepository{
+			WebURL:        repository.Links.HTML.Href,
+			CloneURL:      bb.cloneURL(repository.Links.Clone),
+			SSHURL:        sshLink(repository.Links.Clone),
+			Name:          repository.Slug,
+			DefaultBranch: repository.MainBranch.Name,
+		}, nil
+	}
+
+	repository := bitbucketServerRepository{}
+
+	if err := bb.client().do(http.MethodGet, bb.repositoryPath(), nil, &repository); err != nil {
+		return nil, errors.WithStack(errors.Wrap(err, "Bitbucket error"))
+	}
+
+	// bitbucket server doesn't include the default branch in the repository details
+	defaultBranch := bitbucketServerBranch{}
+
+	if err := bb.client().do(http.MethodGet, bb.repositoryPath()+"/branches/default", nil, &defaultBranch); err != nil {
+		return nil, errors.WithStack(errors.Wrap(err, "Bitbucket error"))
+	}
+
+	return &ProviderRepository{
+		WebURL:        firstLink(repository.Links.Self),
+		CloneURL:      bb.cloneURL(repository.Links.Clone),
+		SSHURL:        sshLink(repository.Links.Clone),
+		Name:          repository.Slug,
+		DefaultBranch: defaultBranch.DisplayID,
+	}, nil
+}
+
+// CreatePullRequest creates a pull request on bitbucket
+func (bb *Bitbucket) CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error) {
+	log.Info().
+		Str("prTitle", bb.PullRequest.Title).
+		Str("prTarget", *targetBranch).
+		Str("prSource", *sourceBranch).
+		Str("repoName", bb.Common.RepositoryName).
+		Msg("Creating Bitbucket pull request")
+
+	// bitbucket doesn't offer a way to auto complete pull requests through its api
+	if bb.PullRequest.AutoComplete {
+		log.Warn().
+			Msg("Pull request auto completion is not supported by bitbucket")
+	}
+
+	if bb.isCloud() {
+		pr := bitbucketCloudPullRequest{}
+
+		err := bb.client().do(http.MethodPost, bb.repositoryPath()+"/pullrequests", map[string]interface{}{
+			"title":               bb.PullRequest.Title,
+			"source":              map[string]interface{}{"branch": map[string]string{"name": *sourceBranch}},
+			"destination":         map[string]interface{}{"branch": map[string]string{"name": *targetBranch}},
+			"close_source_branch": true,
+		}, &pr)
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Bitbucket error")
+		}
+
+		return &ProviderPullRequest{
+			PullRequestID:  strconv.Itoa(pr.ID),
+			PullRequestURL: pr.Links.HTML.Href,
+		}, nil
+	}
+
+	refPrefix := "refs/heads/"
+	pr := bitbucketServerPullRequest{}
+
+	err := bb.client().do(http.MethodPost, bb.repositoryPath()+"/pull-requests", map[string]interface{}{
+		"title":   bb.PullRequest.Title,
+		"fromRef": map[string]string{"id": refPrefix + *sourceBranch},
+		"toRef":   map[string]string{"id": refPrefix + *targetBranch},
+	}, &pr)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Bitbucket error")
+	}
+
+	return &ProviderPullRequest{
+		PullRequestID:  strconv.Itoa(pr.ID),
+		PullRequestURL: firstLink(pr.Links.Self),
+	}, nil
+}
+
+// GetCommonInput returns common inputs provided by the user via cli
+func (bb *Bitbucket) GetCommonInput() *domain.CommonInput {
+	return &bb.Common
+}
+
+// GetPullRequestInput returns pull request inputs provided by the user via cli
+func (bb *Bitbucket) GetPullRequestInput() *domain.PullRequestInput {
+	return &bb.PullRequest
+}
+
+// isCloud tells if BaseURL points to bitbucket cloud, its api or web host, whatever its path and case
+func (bb *Bitbucket) isCloud() bool {
+	if bb.BaseURL == "" {
+		return true
+	}
+
+	baseURL, err := url.Parse(bb.BaseURL)
+
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(baseURL.Hostname(), "api.bitbucket.org") || strings.EqualFold(baseURL.Hostname(), "bitbucket.org")
+}
+
+func (bb *Bitbucket) baseURL() string {
+	if bb.isCloud() {
+		return BitbucketCloudURL
+	}
+
+	return strings.TrimSuffix(bb.BaseURL, "/")
+}
+
+func (bb *Bitbucket) client() *restClient {
+	auth := "Bearer " + bb.Common.Pat
+
+	if bb.Username != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(bb.Username+":"+bb.Common.Pat))
+	}
+
+	headers := map[string]string{
+		"Authorization": auth,
+	}
+
+	if bb.isCloud() {
+		return newRestClient(bb.baseURL()+"/2.0", headers)
+	}
+
+	return newRestClient(bb.baseURL()+"/rest/api/1.0", headers)
+}
+
+func (bb *Bitbucket) repositoryPath() string {
+	if bb.isCloud() {
+		return "/repositories/" + url.PathEscape(bb.Project) + "/" + url.PathEscape(bb.Common.RepositoryName)
+	}
+
+	return "/projects/" + url.PathEscape(bb.Project) + "/repos/" + url.PathEscape(bb.Common.RepositoryName)
+}
+
+// cloneURL picks the http clone link. Bitbucket includes the requesting user in it, which is replaced by the configured
+// username, or by the user bitbucket cloud expects for access tokens.
+func (bb *Bitbucket) cloneURL(links []bitbucketLink) string {
+	for _, link := range links {
+		if link.Name != "http" && link.Name != "https" {
+			continue
+		}
+
+		u, err := url.Parse(link.Href)
+
+		if err != nil {
+			return link.Href
+		}
+
+		switch {
+		case bb.Username != "":
+			u.User = url.User(bb.Username)
+		case bb.isCloud():
+			u.User = url.User("x-token-auth")
+		default:
+			u.User = nil
+		}
+
+		return u.String()
+	}
+
+	return ""
+}
+
+// sshLink returns the ssh clone link, empty if there's none
+func sshLink(links []bitbucketLink) string {
+	for _, link := range links {
+		if link.Name == "ssh" {
+			return link.Href
+		}
+	}
+
+	return ""
+}
+
+func firstLink(links []bitbucketLink) string {
+	if len(links) == 0 {
+		return ""
+	}
+
+	return links[0].Href
+}
@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fuse/internal/domain"
+)
+
+func TestBitbucketServerGetRepository(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path == "/rest/api/1.0/projects/PRJ/repos/repo/branches/default" {
+			_, _ = w.Write([]byte(`{"id":"refs/heads/develop","displayId":"develop"}`))
+			return
+		}
+
+		if r.URL.Path != "/rest/api/1.0/projects/PRJ/repos/repo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"slug":"repo","links":{
+			"clone":[{"href":"ssh://git@bitbucket.local:7999/prj/repo.git","name":"ssh"},
+				{"href":"https://someone@bitbucket.local/scm/prj/repo.git","name":"http"}],
+			"self":[{"href":"https://bitbucket.local/projects/PRJ/repos/repo/browse"}]}}`))
+	}))
+	defer server.Close()
+
+	bb := Bitbucket{
+		BaseURL: server.URL,
+		Project: "PRJ",
+		Common:  domain.CommonInput{RepositoryName: "repo", Pat: "token"},
+	}
+
+	repo, err := bb.GetRepository()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.Name != "repo" || repo.CloneURL != "https://bitbucket.local/scm/prj/repo.git" || repo.DefaultBranch != "develop" ||
+		repo.WebURL != "https://bitbucket.local/projects/PRJ/repos/repo/browse" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+}
+
+func TestBitbucketCloudCloneURL(t *testing.T) {
+	bb := Bitbucket{Project: "workspace"}
+	links := []bitbucketLink{{Href: "https://someone@bitbucket.org/workspace/repo.git", Name: "https"}}
+
+	if u := bb.cloneURL(links); u != "https://x-token-auth@bitbucket.org/workspace/repo.git" {
+		t.Errorf("unexpected clone url %s", u)
+	}
+
+	bb.Username = "me"
+
+	if u := bb.cloneURL(links); u != "https://me@bitbucket.org/workspace/repo.git" {
+		t.Errorf("unexpected clone url %s", u)
+	}
+}
+
+func TestBitbucketIsCloud(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		expected bool
+	}{
+		{"", true},
+		{"https://api.bitbucket.org", true},
+		{"https://api.bitbucket.org/", true},
+		{"https://api.bitbucket.org/2.0/", true},
+		{"HTTPS://API.Bitbucket.org", true},
+		{"https://bitbucket.org", true},
+		{"https://Bitbucket.org/workspace/", true},
+		{"https://notbitbucket.org", false},
+		{"https://bitbucket.mycompany.io", false},
+		{"https://bitbucket.mycompany.io/api.bitbucket.org", false},
+	}
+
+	for _, test := range tests {
+		bb := Bitbucket{BaseURL: test.baseURL}
+
+		if got := bb.isCloud(); got != test.expected {
+			t.Errorf("isCloud(%q) = %v, expected %v", test.baseURL, got, test.expected)
+		}
+
+		if test.expected && bb.baseURL() != BitbucketCloudURL {
+			t.Errorf("expected %q to use the cloud api url, got %q", test.baseURL, bb.baseURL())
+		}
+	}
+
+	if got := (&Bitbucket{BaseURL: "https://bitbucket.mycompany.io/"}).baseURL(); got != "https://bitbucket.mycompany.io" {
+		t.Errorf("expected the server url trailing slash to be dropped, got %q", got)
+	}
+}
@@ -1 +1,331 @@
 package providers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSparsePattern(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"charts/app/values.yaml", "/charts/app/values.yaml"},
+		{".fuse/state.json", "/.fuse/state.json"},
+		{"docs/[draft]*?.md", "/docs/\\[draft]\\*\\?.md"},
+		{"#notes", "/#notes"},
+		{"trailing ", "/trailing\\ "},
+	}
+
+	for _, test := range tests {
+		if got := sparsePattern(test.path); got != test.expected {
+			t.Errorf("sparsePattern(%q) = %q, expected %q", test.path, got, test.expected)
+		}
+	}
+}
+
+func TestGitCloneSparse(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	root, err := ioutil.TempDir("", "fuse-remote")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	seed := filepath.Join(root, "seed")
+
+	for _, path := range []string{"charts/app/values.yaml", "charts/other/values.yaml", "README.md"} {
+		if err = os.MkdirAll(filepath.Dir(filepath.Join(seed, path)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = ioutil.WriteFile(filepath.Join(seed, path), []byte(path+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q", seed},
+		{"-C", seed, "add", "-A"},
+		{"-C", seed, "-c", "user.name=seed", "-c", "user.email=seed@fuse.io", "commit", "-q", "-m", "seed"},
+		{"clone", "-q", "--bare", seed, filepath.Join(root, "remote.git")},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	destination, gitCloneRoot, err := GitClone("file://"+filepath.Join(root, "remote.git"), "remote", "", "",
+		CloneOptions{Shallow: true, SparsePaths: []string{"charts/app/values.yaml", ".fuse/state.json"}})
+
+	if destination != "" {
+		defer os.RemoveAll(destination)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = os.Stat(filepath.Join(gitCloneRoot, "charts/app/values.yaml")); err != nil {
+		t.Errorf("expected the sparse path to be checked out: %v", err)
+	}
+
+	for _, path := range []string{"charts/other/values.yaml", "README.md"} {
+		if _, err = os.Stat(filepath.Join(gitCloneRoot, path)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be checked out, got %v", path, err)
+		}
+	}
+
+	// blobs of files not checked out are still read from the history
+	sha, err := exec.Command("git", "-C", gitCloneRoot, "rev-parse", "HEAD:README.md").Output()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := GitReadBlob(gitCloneRoot, strings.TrimSpace(string(sha))); err != nil || string(content) != "README.md\n" {
+		t.Errorf("unexpected blob content %q, %v", content, err)
+	}
+}
+
+// newHTTPRemote serves a bare repository, seeded with a commit on master, through git http-backend requiring basic
+// authentication with the token as password. It returns the server, serving the repository at /remote.git, and its path.
+func newHTTPRemote(t *testing.T, token string) (*httptest.Server, string) {
+	t.Helper()
+
+	gitPath, err := exec.LookPath("git")
+
+	if err != nil {
+		t.Skip("git is not available")
+	}
+
+	root, err := ioutil.TempDir("", "fuse-remote")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed, bare := filepath.Join(root, "seed"), filepath.Join(root, "remote.git")
+
+	if err = os.MkdirAll(seed, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(seed, "README.md"), []byte("seed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q", seed},
+		{"-C", seed, "checkout", "-q", "-b", "master"},
+		{"-C", seed, "add", "-A"},
+		{"-C", seed, "-c", "user.name=seed", "-c", "user.email=seed@fuse.io", "commit", "-q", "-m", "seed"},
+		{"clone", "-q", "--bare", seed, bare},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	backend := &cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1", "REMOTE_USER=fuse"},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, password, ok := r.BasicAuth(); !ok || password != token {
+			w.Header().Set("WWW-Authenticate", `Basic realm="fuse"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		backend.ServeHTTP(w, r)
+	}))
+
+	return server, bare
+}
+
+func TestGitClientCredentials(t *testing.T) {
+	for _, client := range []GitClient{&ShellGit{}, &NativeGit{}} {
+		t.Run(reflect.TypeOf(client).Elem().Name(), func(t *testing.T) {
+			server, bare := newHTTPRemote(t, "s3cret")
+			defer server.Close()
+			defer os.RemoveAll(filepath.Dir(bare))
+
+			// the git credential prompt would hang without a terminal
+			os.Setenv("GIT_TERMINAL_PROMPT", "0")
+			defer os.Unsetenv("GIT_TERMINAL_PROMPT")
+
+			destination, _, err := client.Clone(server.URL+"/remote.git", "remote", "wrong", "master", CloneOptions{})
+			os.RemoveAll(destination)
+
+			if err == nil {
+				t.Fatal("expected the clone to fail with a wrong token")
+			}
+
+			destination, root, err := client.Clone(server.URL+"/remote.git", "remote", "s3cret", "master", CloneOptions{})
+			defer os.RemoveAll(destination)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := ioutil.ReadFile(filepath.Join(root, ".git", "config"))
+
+			if err != nil || strings.Contains(string(config), "s3cret") {
+				t.Errorf("expected the clone config not to hold the token, got %v %s", err, config)
+			}
+
+			if err = ioutil.WriteFile(filepath.Join(root, "new.txt"), []byte("new\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, step := range []func() error{
+				func() error { return client.Configure(root) },
+				func() error { return client.Commit(root) },
+				func() error { return client.Push(root, "master") },
+			} {
+				if err = step(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			out, err := exec.Command("git", "-C", bare, "show", "master:new.txt").CombinedOutput()
+
+			if err != nil || string(out) != "new\n" {
+				t.Errorf("expected new.txt to be pushed, got %v %s", err, out)
+			}
+		})
+	}
+}
+
+func TestIsSSHURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"git@github.com:org/repository.git", true},
+		{"github.com:org/repository.git", true},
+		{"ssh://git@ssh.dev.azure.com/v3/org/project/repository", true},
+		{"git+ssh://git@gitlab.com/org/repository.git", true},
+		{"https://github.com/org/repository", false},
+		{"https://x-token-auth@bitbucket.org/org/repository.git", false},
+		{"file:///srv/git/repository.git", false},
+		{"/srv/git/repository.git", false},
+		{"./repo:sitory", false},
+	}
+
+	for _, test := range tests {
+		if got := IsSSHURL(test.url); got != test.expected {
+			t.Errorf("IsSSHURL(%q) = %v, expected %v", test.url, got, test.expected)
+		}
+	}
+}
+
+func TestSSHCommand(t *testing.T) {
+	tests := []struct {
+		key        string
+		knownHosts string
+		expected   string
+	}{
+		{"/keys/deploy", "", "ssh -i '/keys/deploy' -o IdentitiesOnly=yes"},
+		{"/keys/deploy", "/keys/known_hosts",
+			"ssh -i '/keys/deploy' -o IdentitiesOnly=yes -o UserKnownHostsFile='/keys/known_hosts' -o StrictHostKeyChecking=yes"},
+		{"/keys/it's", "", `ssh -i '/keys/it'\''s' -o IdentitiesOnly=yes`},
+	}
+
+	for _, test := range tests {
+		if got := sshCommand(test.key, test.knownHosts); got != test.expected {
+			t.Errorf("sshCommand(%q, %q) = %q, expected %q", test.key, test.knownHosts, got, test.expected)
+		}
+	}
+}
+
+func TestGitCloneSSH(t *testing.T) {
+	// only the seeded bare repository is used
+	server, bare := newHTTPRemote(t, "unused")
+	server.Close()
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	// a fake ssh running the remote git command locally, logging the options it's given
+	bin, err := ioutil.TempDir("", "fuse-ssh")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(bin)
+
+	sshLog := filepath.Join(bin, "ssh.log")
+	script := "#!/bin/sh\necho \"$@\" >> " + sshLog + "\nfor last; do :; done\nexec sh -c \"$last\"\n"
+
+	if err = ioutil.WriteFile(filepath.Join(bin, "ssh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	client := &ShellGit{}
+	options := CloneOptions{SSHKey: "/keys/deploy", KnownHosts: "/keys/known_hosts"}
+	destination, root, err := client.Clone("ssh://git@fake"+bare, "remote", "unused", "master", options)
+	defer os.RemoveAll(destination)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(root, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []func() error{
+		func() error { return client.Configure(root) },
+		func() error { return client.Commit(root) },
+		func() error { return client.Push(root, "master") },
+	} {
+		if err = step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := exec.Command("git", "-C", bare, "show", "master:new.txt").CombinedOutput()
+
+	if err != nil || string(out) != "new\n" {
+		t.Errorf("expected new.txt to be pushed, got %v %s", err, out)
+	}
+
+	calls, err := ioutil.ReadFile(sshLog)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the clone and the push both authenticate with the key
+	lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("expected ssh to be called twice, got %q", lines)
+	}
+
+	for _, line := range lines {
+		if !strings.Contains(line, "-i /keys/deploy -o IdentitiesOnly=yes -o UserKnownHostsFile=/keys/known_hosts") {
+			t.Errorf("expected ssh to use the key and known hosts, got %q", line)
+		}
+	}
+}
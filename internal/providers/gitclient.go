@@ -0,0 +1,123 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Git clients, chosen through domain.CommonInput.GitClient
+const (
+	// GitClientNative uses an in process git implementation, without requiring a git binary
+	GitClientNative = "native"
+	// GitClientShell shells out to the git binary
+	GitClientShell = "shell"
+	// GitClientAPI reads and commits the content directory files through the hosting api, see APIGit
+	GitClientAPI = "api"
+)
+
+// GitClient performs the git operations of the fuse workflow on a local clone.
+// Implementations may keep state, e.g. credentials, between operations so a client must not be shared between clones.
+type GitClient interface {
+	Clone(repositoryURL, repositoryName, token, branch string, options CloneOptions) (destination, gitCloneRoot string, err error)
+	Configure(repositoryDir string) error
+	CurrentBranch(repositoryDir string) (string, error)
+	CreateBranch(repositoryDir, branchName string) error
+	Commit(repositoryDir string) error
+	HeadSHA(repositoryDir string) (string, error)
+	ReadBlob(repositoryDir, sha string) ([]byte, error)
+	Tag(repositoryDir, tag string) error
+	Push(repositoryDir, branch string) error
+}
+
+// ValidateGitClient checks the git client is empty, meaning the provider default, native, shell or api
+func ValidateGitClient(name string) error {
+	switch name {
+	case "", GitClientNative, GitClientShell, GitClientAPI:
+		return nil
+	}
+
+	return errors.Errorf("invalid git client %q, expected native, shell or api", name)
+}
+
+// NewGitClient returns the git client configured for the provider. By default azure devops uses the shell client, as the
+// native one has issues with it, and so do sparse clones, the native one doesn't support them. Otherwise the native client is used.
+// The api client is only available to providers implementing APIProvider.
+func NewGitClient(provider Provider) (GitClient, error) {
+	name := provider.GetCommonInput().GitClient
+
+	if name == GitClientAPI {
+		apiProvider, ok := provider.(APIProvider)
+
+		if !ok {
+			return nil, errors.Errorf("the api git client is not supported by %T", provider)
+		}
+
+		return NewAPIGit(apiProvider), nil
+	}
+
+	if name == "" {
+		name = GitClientNative
+
+		if _, isAzure := provider.(*AzureDevOps); isAzure || provider.GetCommonInput().Sparse {
+			name = GitClientShell
+		}
+	}
+
+	if name == GitClientShell {
+		return &ShellGit{}, nil
+	}
+
+	return &NativeGit{}, nil
+}
+
+// ShellGit is the GitClient shelling out to the git binary. It keeps the clone token to push with it.
+type ShellGit struct {
+	token string
+}
+
+// Clone clones the repository, see GitClone
+func (g *ShellGit) Clone(repositoryURL, repositoryName, token, branch string, options CloneOptions) (destination, gitCloneRoot string, err error) {
+	g.token = token
+
+	return GitClone(repositoryURL, repositoryName, token, branch, options)
+}
+
+// Configure configures the git user, see ConfigureGit
+func (g *ShellGit) Configure(repositoryDir string) error {
+	return ConfigureGit(&repositoryDir)
+}
+
+// CurrentBranch returns the checked out branch, see GitCurrentBranch
+func (g *ShellGit) CurrentBranch(repositoryDir string) (string, error) {
+	return GitCurrentBranch(repositoryDir)
+}
+
+// CreateBranch creates and checks out a branch, see CreateGitBranch
+func (g *ShellGit) CreateBranch(repositoryDir, branchName string) error {
+	return CreateGitBranch(repositoryDir, branchName)
+}
+
+// Commit commits every change, see GitCommit
+func (g *ShellGit) Commit(repositoryDir string) error {
+	return GitCommit(repositoryDir)
+}
+
+// HeadSHA returns the HEAD commit sha, see GitHeadSHA
+func (g *ShellGit) HeadSHA(repositoryDir string) (string, error) {
+	return GitHeadSHA(repositoryDir)
+}
+
+// ReadBlob reads a blob of the repository history, see GitReadBlob
+func (g *ShellGit) ReadBlob(repositoryDir, sha string) ([]byte, error) {
+	return GitReadBlob(repositoryDir, sha)
+}
+
+// Tag creates an annotated tag, see GitTag
+func (g *ShellGit) Tag(repositoryDir, tag string) error {
+	return GitTag(repositoryDir, tag)
+}
+
+// Push pushes the branch and its tags, see GitPush
+func (g *ShellGit) Push(repositoryDir, branch string) error {
+	return GitPush(repositoryDir, branch, g.token)
+}
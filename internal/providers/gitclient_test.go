@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+
+	"fuse/internal/domain"
+)
+
+func TestNewGitClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		expected GitClient
+	}{
+		{"native by default", &GitRemote{}, &NativeGit{}},
+		{"shell for azure devops", &AzureDevOps{}, &ShellGit{}},
+		{"configured shell", &GitHub{Common: domain.CommonInput{GitClient: GitClientShell}}, &ShellGit{}},
+		{"configured native for azure devops", &AzureDevOps{Common: domain.CommonInput{GitClient: GitClientNative}}, &NativeGit{}},
+		{"configured api for github", &GitHub{Common: domain.CommonInput{GitClient: GitClientAPI}}, &APIGit{}},
+		{"configured api for azure devops", &AzureDevOps{Common: domain.CommonInput{GitClient: GitClientAPI}}, &APIGit{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, err := NewGitClient(test.provider)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if reflect.TypeOf(client) != reflect.TypeOf(test.expected) {
+				t.Errorf("expected %T, got %T", test.expected, client)
+			}
+		})
+	}
+
+	if _, err := NewGitClient(&GitRemote{Common: domain.CommonInput{GitClient: GitClientAPI}}); err == nil {
+		t.Error("expected git remotes not to support the api git client")
+	}
+
+	if err := ValidateGitClient("libgit2"); err == nil {
+		t.Error("expected an invalid git client error")
+	}
+}
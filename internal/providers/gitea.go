@@ -0,0 +1,125 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"fuse/internal/domain"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Gitea encapsulates gitea metadata and bridges communication to gitea (and forgejo) provider.
+// BaseURL points to the gitea instance, e.g: https://gitea.mycompany.io
+type Gitea struct {
+	BaseURL     string
+	Owner       string
+	Common      domain.CommonInput
+	PullRequest domain.PullRequestInput
+}
+
+type giteaRepository struct {
+	Name          string `json:"name"`
+	HTMLURL       string `json:"html_url"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type giteaPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// GetRepository will fetch the repository details on gitea
+func (gt *Gitea) GetRepository() (*ProviderRepository, error) {
+	log.Info().
+		Str("baseUrl", gt.BaseURL).
+		Str("owner", gt.Owner).
+		Str("repoName", gt.Common.RepositoryName).
+		Msg("Getting gitea repository")
+
+	repository := giteaRepository{}
+
+	if err := gt.client().do(http.MethodGet, gt.repositoryPath(), nil, &repository); err != nil {
+		return nil, errors.WithStack(errors.Wrap(err, "Gitea error"))
+	}
+
+	return &ProviderRepository{
+		WebURL:        repository.HTMLURL,
+		CloneURL:      repository.CloneURL,
+		SSHURL:        repository.SSHURL,
+		Name:          repository.Name,
+		DefaultBranch: repository.DefaultBranch,
+	}, nil
+}
+
+// CreatePullRequest creates a pull request on gitea
+func (gt *Gitea) CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error) {
+	log.Info().
+		Str("prTitle", gt.PullRequest.Title).
+		Str("prTarget", *targetBranch).
+		Str("prSource", *sourceBranch).
+		Str("repoName", gt.Common.RepositoryName).
+		Msg("Creating Gitea pull request")
+
+	client := gt.client()
+	pr := giteaPullRequest{}
+
+	err := client.do(http.MethodPost, gt.repositoryPath()+"/pulls", map[string]interface{}{
+		"title": gt.PullRequest.Title,
+		"head":  *sourceBranch,
+		"base":  *targetBranch,
+	}, &pr)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Gitea error")
+	}
+
+	// scheduled merges are only available on recent gitea and forgejo versions. Older ones keep the pull request open.
+	if gt.PullRequest.AutoComplete {
+		err = client.do(http.MethodPost, gt.repositoryPath()+"/pulls/"+strconv.Itoa(pr.Number)+"/merge",
+			map[string]interface{}{
+				"Do":                        "merge",
+				"merge_when_checks_succeed": true,
+				"delete_branch_after_merge": true,
+			}, nil)
+
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Int("pullRequestNumber", pr.Number).
+				Msg("Unable to set pull request auto merge")
+		}
+	}
+
+	return &ProviderPullRequest{
+		PullRequestID:  strconv.Itoa(pr.Number),
+		PullRequestURL: pr.HTMLURL,
+	}, nil
+}
+
+// GetCommonInput returns common inputs provided by the user via cli
+func (gt *Gitea) GetCommonInput() *domain.CommonInput {
+	return &gt.Common
+}
+
+// GetPullRequestInput returns pull request inputs provided by the user via cli
+func (gt *Gitea) GetPullRequestInput() *domain.PullRequestInput {
+	return &gt.PullRequest
+}
+
+// client returns the api client, the base url may end with a / as copied from a browser
+func (gt *Gitea) client() *restClient {
+	return newRestClient(strings.TrimSuffix(gt.BaseURL, "/")+"/api/v1", map[string]string{
+		"Authorization": "token " + gt.Common.Pat,
+	})
+}
+
+func (gt *Gitea) repositoryPath() string {
+	return "/repos/" + url.PathEscape(gt.Owner) + "/" + url.PathEscape(gt.Common.RepositoryName)
+}
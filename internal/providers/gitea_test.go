@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fuse/internal/domain"
+)
+
+func TestGiteaPullRequestWithAutoMerge(t *testing.T) {
+	merged := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/repos/owner/repo":
+			_, _ = w.Write([]byte(`{"name":"repo","html_url":"http://gitea.local/owner/repo",
+				"clone_url":"http://gitea.local/owner/repo.git","default_branch":"main"}`))
+		case "POST /api/v1/repos/owner/repo/pulls":
+			body := map[string]string{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if body["head"] != "feature" || body["base"] != "main" {
+				t.Errorf("unexpected pull request body %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"number":3,"html_url":"http://gitea.local/owner/repo/pulls/3"}`))
+		case "POST /api/v1/repos/owner/repo/pulls/3/merge":
+			merged = true
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	gt := Gitea{
+		BaseURL:     server.URL,
+		Owner:       "owner",
+		Common:      domain.CommonInput{RepositoryName: "repo", Pat: "token"},
+		PullRequest: domain.PullRequestInput{Title: "title", Enabled: true, AutoComplete: true},
+	}
+
+	repo, err := gt.GetRepository()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.CloneURL != "http://gitea.local/owner/repo.git" || repo.Name != "repo" || repo.DefaultBranch != "main" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+
+	source := "feature"
+	pr, err := gt.CreatePullRequest(&source, &repo.DefaultBranch)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.PullRequestID != "3" || pr.PullRequestURL != "http://gitea.local/owner/repo/pulls/3" || !merged {
+		t.Errorf("unexpected pull request %+v, auto merge requested: %v", pr, merged)
+	}
+}
+
+func TestGiteaBaseURLTrailingSlash(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/owner/repo" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"name":"repo","default_branch":"main"}`))
+	}))
+	defer server.Close()
+
+	gt := Gitea{
+		BaseURL: server.URL + "/",
+		Owner:   "owner",
+		Common:  domain.CommonInput{RepositoryName: "repo", Pat: "token"},
+	}
+
+	if _, err := gt.GetRepository(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,7 +3,11 @@ package providers
 
 import (
 	"context"
+	"encoding/base64"
+	"os"
+	"path"
 	"strconv"
+	"strings"
 
 	"golang.org/x/oauth2"
 
@@ -26,12 +30,10 @@ type GitHub struct {
 func (gh *GitHub) GetRepository() (*ProviderRepository, error) {
 	log.Info().
 		Str("repo", gh.Common.RepositoryName).
-		Str("pat", gh.Common.Pat).
 		Msg("Getting github repository")
 
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gh.Common.Pat})
-	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	client := gh.client(ctx)
 
 	repository, _, err := client.Repositories.Get(ctx, gh.Owner, gh.Common.RepositoryName)
 
@@ -40,16 +42,18 @@ func (gh *GitHub) GetRepository() (*ProviderRepository, error) {
 	}
 
 	return &ProviderRepository{
-		WebURL: *repository.HTMLURL,
-		Name:   *repository.Name,
+		WebURL:        *repository.HTMLURL,
+		SSHURL:        repository.GetSSHURL(),
+		Name:          *repository.Name,
+		DefaultBranch: repository.GetDefaultBranch(),
 	}, nil
 }
 
 // CreatePullRequest creates a pull request on github
-func (gh *GitHub) CreatePullRequest(sourceBranch *string) (*ProviderPullRequest, error) {
+func (gh *GitHub) CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error) {
 	log.Info().
 		Str("prTitle", gh.PullRequest.Title).
-		Str("prTarget", TargetBranch).
+		Str("prTarget", *targetBranch).
 		Str("prSource", *sourceBranch).
 		Str("repoName", gh.Common.RepositoryName).
 		Msg("Creating GitHub pull request")
@@ -59,14 +63,11 @@ func (gh *GitHub) CreatePullRequest(sourceBranch *string) (*ProviderPullRequest,
 	prTitle := "Fuse Automated - " + prID
 
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gh.Common.Pat})
-	client := github.NewClient(oauth2.NewClient(ctx, ts))
-	targetBranch := TargetBranch
-
+	client := gh.client(ctx)
 	ghpr, _, err := client.PullRequests.Create(ctx, gh.Owner, gh.Common.RepositoryName, &github.NewPullRequest{
 		Title: &prTitle,
 		Head:  sourceBranch,
-		Base:  &targetBranch,
+		Base:  targetBranch,
 	})
 
 	if err != nil {
@@ -79,6 +80,236 @@ func (gh *GitHub) CreatePullRequest(sourceBranch *string) (*ProviderPullRequest,
 	}, nil
 }
 
+// BranchSHA returns the commit sha the branch points to
+func (gh *GitHub) BranchSHA(branch string) (string, error) {
+	ctx := context.Background()
+	ref, _, err := gh.client(ctx).Git.GetRef(ctx, gh.Owner, gh.Common.RepositoryName, "refs/heads/"+branch)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Github error")
+	}
+
+	return ref.GetObject().GetSHA(), nil
+}
+
+// ReadBlob reads the content of the blob sha through the git data api
+func (gh *GitHub) ReadBlob(sha string) ([]byte, error) {
+	ctx := context.Background()
+	content, _, err := gh.client(ctx).Git.GetBlobRaw(ctx, gh.Owner, gh.Common.RepositoryName, sha)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Github error")
+	}
+
+	return content, nil
+}
+
+// ReadFiles reads the files at the given paths as of the commit sha through the git data api, walking the trees of their
+// directories. Paths that don't exist, or are not files, are left out.
+func (gh *GitHub) ReadFiles(commitSHA string, paths []string) (map[string]RemoteFile, error) {
+	ctx := context.Background()
+	client := gh.client(ctx)
+
+	commit, _, err := client.Git.GetCommit(ctx, gh.Owner, gh.Common.RepositoryName, commitSHA)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Github error")
+	}
+
+	// entries of the trees read so far by directory, the root being the empty directory
+	trees := map[string]map[string]*github.TreeEntry{}
+	treeSHAs := map[string]string{"": commit.GetTree().GetSHA()}
+	files := map[string]RemoteFile{}
+
+	for _, filePath := range paths {
+		dir, name := path.Split(filePath)
+		entries, err := gh.treeEntries(ctx, client, trees, treeSHAs, strings.TrimSuffix(dir, "/"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		entry, exists := entries[name]
+
+		if !exists || entry.GetType() != "blob" {
+			continue
+		}
+
+		if entry.GetMode() == "120000" {
+			return nil, errors.Errorf("Github error: %s is a symlink, not supported by the api git client", filePath)
+		}
+
+		content, _, err := client.Git.GetBlobRaw(ctx, gh.Owner, gh.Common.RepositoryName, entry.GetSHA())
+
+		if err != nil {
+			return nil, errors.Wrap(err, "Github error")
+		}
+
+		mode := os.FileMode(0644)
+
+		if entry.GetMode() == "100755" {
+			mode = 0755
+		}
+
+		files[filePath] = RemoteFile{Content: content, Mode: mode}
+	}
+
+	return files, nil
+}
+
+// treeEntries returns the entries of the tree of dir, reading the trees of its parents as needed. Missing directories have none.
+func (gh *GitHub) treeEntries(ctx context.Context, client *github.Client, trees map[string]map[string]*github.TreeEntry,
+	treeSHAs map[string]string, dir string) (map[string]*github.TreeEntry, error) {
+	if entries, read := trees[dir]; read {
+		return entries, nil
+	}
+
+	if _, known := treeSHAs[dir]; !known {
+		parent, name := path.Split(dir)
+		parentEntries, err := gh.treeEntries(ctx, client, trees, treeSHAs, strings.TrimSuffix(parent, "/"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		entry, exists := parentEntries[name]
+
+		if !exists || entry.GetType() != "tree" {
+			trees[dir] = map[string]*github.TreeEntry{}
+			return trees[dir], nil
+		}
+
+		treeSHAs[dir] = entry.GetSHA()
+	}
+
+	tree, _, err := client.Git.GetTree(ctx, gh.Owner, gh.Common.RepositoryName, treeSHAs[dir], false)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Github error")
+	}
+
+	trees[dir] = map[string]*github.TreeEntry{}
+
+	for _, entry := range tree.Entries {
+		trees[dir][entry.GetPath()] = entry
+	}
+
+	return trees[dir], nil
+}
+
+// PushFiles creates the blobs, tree and commit of the changes through the git data api and points the branch to the commit
+func (gh *GitHub) PushFiles(branch, parentSHA string, newBranch bool, changes []FileChange, message string) (string, error) {
+	log.Info().
+		Str("branch", branch).
+		Int("files", len(changes)).
+		Msg("Creating GitHub commit")
+
+	ctx := context.Background()
+	client := gh.client(ctx)
+
+	parent, _, err := client.Git.GetCommit(ctx, gh.Owner, gh.Common.RepositoryName, parentSHA)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Github error")
+	}
+
+	var entries []*github.TreeEntry
+
+	for _, change := range changes {
+		entry := &github.TreeEntry{
+			Path: github.String(change.Path),
+			Mode: github.String("100644"),
+			Type: github.String("blob"),
+		}
+
+		if change.Mode&0100 != 0 {
+			entry.Mode = github.String("100755")
+		}
+
+		// entries without sha nor content are deleted
+		if !change.Deleted {
+			blob, _, err := client.Git.CreateBlob(ctx, gh.Owner, gh.Common.RepositoryName, &github.Blob{
+				Content:  github.String(base64.StdEncoding.EncodeToString(change.Content)),
+				Encoding: github.String("base64"),
+			})
+
+			if err != nil {
+				return "", errors.Wrap(err, "Github error")
+			}
+
+			entry.SHA = blob.SHA
+		}
+
+		entries = append(entries, entry)
+	}
+
+	tree, _, err := client.Git.CreateTree(ctx, gh.Owner, gh.Common.RepositoryName, parent.GetTree().GetSHA(), entries)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Github error")
+	}
+
+	commit, _, err := client.Git.CreateCommit(ctx, gh.Owner, gh.Common.RepositoryName, &github.Commit{
+		Message: github.String(message),
+		Tree:    &github.Tree{SHA: tree.SHA},
+		Parents: []*github.Commit{{SHA: github.String(parentSHA)}},
+	})
+
+	if err != nil {
+		return "", errors.Wrap(err, "Github error")
+	}
+
+	ref := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: commit.SHA},
+	}
+
+	if newBranch {
+		_, _, err = client.Git.CreateRef(ctx, gh.Owner, gh.Common.RepositoryName, ref)
+	} else {
+		_, _, err = client.Git.UpdateRef(ctx, gh.Owner, gh.Common.RepositoryName, ref, false)
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, "Github error")
+	}
+
+	return commit.GetSHA(), nil
+}
+
+// CreateTag creates an annotated tag object of the commit sha and its reference
+func (gh *GitHub) CreateTag(tag, commitSHA, message string) error {
+	ctx := context.Background()
+	client := gh.client(ctx)
+
+	tagObject, _, err := client.Git.CreateTag(ctx, gh.Owner, gh.Common.RepositoryName, &github.Tag{
+		Tag:     github.String(tag),
+		Message: github.String(message),
+		Object:  &github.GitObject{Type: github.String("commit"), SHA: github.String(commitSHA)},
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Github error")
+	}
+
+	_, _, err = client.Git.CreateRef(ctx, gh.Owner, gh.Common.RepositoryName, &github.Reference{
+		Ref:    github.String("refs/tags/" + tag),
+		Object: &github.GitObject{SHA: tagObject.SHA},
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Github error")
+	}
+
+	return nil
+}
+
+func (gh *GitHub) client(ctx context.Context) *github.Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: gh.Common.Pat})
+
+	return github.NewClient(oauth2.NewClient(ctx, ts))
+}
+
 // GetCommonInput returns common inputs provided by the user via cli
 func (gh *GitHub) GetCommonInput() *domain.CommonInput {
 	return &gh.Common
@@ -0,0 +1,134 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"fuse/internal/domain"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// GitLabDefaultURL is the gitlab url used when no self hosted base url is provided
+const GitLabDefaultURL = "https://gitlab.com"
+
+// GitLab encapsulates gitlab metadata and bridges communication to gitlab provider.
+// BaseURL points to the gitlab instance, e.g: https://gitlab.com or a self hosted https://gitlab.mycompany.io
+type GitLab struct {
+	BaseURL     string
+	Namespace   string
+	Common      domain.CommonInput
+	PullRequest domain.PullRequestInput
+}
+
+type gitLabProject struct {
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	WebURL        string `json:"web_url"`
+	SSHURL        string `json:"ssh_url_to_repo"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type gitLabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+}
+
+// GetRepository will fetch the project details on gitlab
+func (gl *GitLab) GetRepository() (*ProviderRepository, error) {
+	log.Info().
+		Str("baseUrl", gl.baseURL()).
+		Str("namespace", gl.Namespace).
+		Str("repoName", gl.Common.RepositoryName).
+		Msg("Getting gitlab repository")
+
+	project := gitLabProject{}
+
+	if err := gl.client().do(http.MethodGet, gl.projectPath(), nil, &project); err != nil {
+		return nil, errors.WithStack(errors.Wrap(err, "GitLab error"))
+	}
+
+	// git names the clone directory after the project path, not the display name
+	return &ProviderRepository{
+		WebURL:        project.WebURL,
+		SSHURL:        project.SSHURL,
+		Name:          project.Path,
+		DefaultBranch: project.DefaultBranch,
+	}, nil
+}
+
+// CreatePullRequest creates a merge request on gitlab
+func (gl *GitLab) CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error) {
+	log.Info().
+		Str("prTitle", gl.PullRequest.Title).
+		Str("prTarget", *targetBranch).
+		Str("prSource", *sourceBranch).
+		Str("repoName", gl.Common.RepositoryName).
+		Msg("Creating GitLab merge request")
+
+	client := gl.client()
+	mr := gitLabMergeRequest{}
+
+	err := client.do(http.MethodPost, gl.projectPath()+"/merge_requests", map[string]interface{}{
+		"source_branch":        *sourceBranch,
+		"target_branch":        *targetBranch,
+		"title":                gl.PullRequest.Title,
+		"remove_source_branch": true,
+	}, &mr)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "GitLab error")
+	}
+
+	// gitlab merges once the pipeline succeeds. If there's no pipeline the request fails and the mr is kept open.
+	if gl.PullRequest.AutoComplete {
+		err = client.do(http.MethodPut, gl.projectPath()+"/merge_requests/"+strconv.Itoa(mr.IID)+"/merge",
+			map[string]interface{}{
+				"merge_when_pipeline_succeeds": true,
+			}, nil)
+
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Int("mergeRequestIID", mr.IID).
+				Msg("Unable to set merge request auto merge")
+		}
+	}
+
+	return &ProviderPullRequest{
+		PullRequestID:  strconv.Itoa(mr.IID),
+		PullRequestURL: mr.WebURL,
+	}, nil
+}
+
+// GetCommonInput returns common inputs provided by the user via cli
+func (gl *GitLab) GetCommonInput() *domain.CommonInput {
+	return &gl.Common
+}
+
+// GetPullRequestInput returns pull request inputs provided by the user via cli
+func (gl *GitLab) GetPullRequestInput() *domain.PullRequestInput {
+	return &gl.PullRequest
+}
+
+func (gl *GitLab) baseURL() string {
+	if gl.BaseURL == "" {
+		return GitLabDefaultURL
+	}
+
+	return gl.BaseURL
+}
+
+func (gl *GitLab) client() *restClient {
+	return newRestClient(gl.baseURL()+"/api/v4", map[string]string{
+		"PRIVATE-TOKEN": gl.Common.Pat,
+	})
+}
+
+// projects are addressed by their url encoded full path, e.g: /projects/group%2Fsubgroup%2Fproject
+func (gl *GitLab) projectPath() string {
+	return "/projects/" + url.PathEscape(gl.Namespace+"/"+gl.Common.RepositoryName)
+}
@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fuse/internal/domain"
+)
+
+func newGitLabStub(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.EscapedPath() {
+		case "GET /api/v4/projects/group%2Fsub%2Frepo":
+			_, _ = w.Write([]byte(`{"id":1,"name":"Repo","path":"repo","web_url":"http://gitlab.local/group/sub/repo"}`))
+		case "POST /api/v4/projects/group%2Fsub%2Frepo/merge_requests":
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if body["source_branch"] != "feature" || body["target_branch"] != "develop" || body["title"] != "title" {
+				t.Errorf("unexpected merge request body %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":100,"iid":7,"web_url":"http://gitlab.local/group/sub/repo/-/merge_requests/7"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGitLabGetRepository(t *testing.T) {
+	server := newGitLabStub(t)
+	defer server.Close()
+
+	gl := GitLab{
+		BaseURL:   server.URL,
+		Namespace: "group/sub",
+		Common:    domain.CommonInput{RepositoryName: "repo", Pat: "token"},
+	}
+
+	repo, err := gl.GetRepository()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.Name != "repo" || repo.WebURL != "http://gitlab.local/group/sub/repo" {
+		t.Errorf("unexpected repository %+v", repo)
+	}
+}
+
+func TestGitLabCreatePullRequest(t *testing.T) {
+	server := newGitLabStub(t)
+	defer server.Close()
+
+	gl := GitLab{
+		BaseURL:     server.URL,
+		Namespace:   "group/sub",
+		Common:      domain.CommonInput{RepositoryName: "repo", Pat: "token"},
+		PullRequest: domain.PullRequestInput{Title: "title", Enabled: true},
+	}
+	source, target := "feature", "develop"
+
+	pr, err := gl.CreatePullRequest(&source, &target)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.PullRequestID != "7" || pr.PullRequestURL != "http://gitlab.local/group/sub/repo/-/merge_requests/7" {
+		t.Errorf("unexpected merge request %+v", pr)
+	}
+}
+
+func TestGitLabUnauthorized(t *testing.T) {
+	server := newGitLabStub(t)
+	defer server.Close()
+
+	gl := GitLab{
+		BaseURL:   server.URL,
+		Namespace: "group/sub",
+		Common:    domain.CommonInput{RepositoryName: "repo", Pat: "wrong"},
+	}
+
+	if _, err := gl.GetRepository(); err == nil {
+		t.Error("expected an error for an unauthorized request")
+	}
+}
@@ -0,0 +1,69 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"fuse/internal/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+// GitRemote targets any git remote without a hosting api, e.g: an internal git server or a local bare repository
+// (file:///srv/git/repository.git). Since there's no api, pull requests are replaced by writing the pushed branch to Output.
+type GitRemote struct {
+	URL         string
+	Common      domain.CommonInput
+	PullRequest domain.PullRequestInput
+}
+
+// Output is where the branches of git remote pull requests are written, stdout unless something else is written there
+var Output io.Writer = os.Stdout
+
+// GetRepository returns the configured remote. Nothing is fetched until the repository is cloned, thus the default
+// branch is only known after cloning. The remote is also the ssh url when it uses the ssh transport.
+func (gr *GitRemote) GetRepository() (*ProviderRepository, error) {
+	log.Info().
+		Str("url", gr.URL).
+		Str("repoName", gr.Common.RepositoryName).
+		Msg("Using git remote")
+
+	repository := &ProviderRepository{
+		WebURL: gr.URL,
+		Name:   gr.Common.RepositoryName,
+	}
+
+	if IsSSHURL(gr.URL) {
+		repository.SSHURL = gr.URL
+	}
+
+	return repository, nil
+}
+
+// CreatePullRequest writes the source branch name to Output so it can be picked up by whoever merges it.
+// The branch name is used as pull request id.
+func (gr *GitRemote) CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error) {
+	log.Info().
+		Str("prTarget", *targetBranch).
+		Str("prSource", *sourceBranch).
+		Str("repoName", gr.Common.RepositoryName).
+		Msg("Git remotes have no pull requests. Skipping pull request creation")
+
+	fmt.Fprintln(Output, *sourceBranch)
+
+	return &ProviderPullRequest{
+		PullRequestID: *sourceBranch,
+	}, nil
+}
+
+// GetCommonInput returns common inputs provided by the user via cli
+func (gr *GitRemote) GetCommonInput() *domain.CommonInput {
+	return &gr.Common
+}
+
+// GetPullRequestInput returns pull request inputs provided by the user via cli
+func (gr *GitRemote) GetPullRequestInput() *domain.PullRequestInput {
+	return &gr.PullRequest
+}
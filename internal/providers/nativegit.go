@@ -0,0 +1,363 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// NativeGit is the GitClient implemented in process with go-git. It behaves like ShellGit, without requiring a git binary
+// for http remotes. It keeps the clone credentials and the created tags to push them.
+type NativeGit struct {
+	auth transport.AuthMethod
+	tags []string
+}
+
+// signature is the author of fuse commits and tags, the same ConfigureGit sets for the shell client
+func signature() *object.Signature {
+	return &object.Signature{
+		Name:  "Fuse",
+		Email: "fuse@dev.io",
+		When:  time.Now(),
+	}
+}
+
+// Clone clones the repository to a temporary directory the caller is responsible to clean.
+// http remotes use basic authentication with the token, like GitClone, and ssh remotes the clone options key.
+// If branch is empty, the remote default branch is checked out. Sparse clones are not supported.
+func (g *NativeGit) Clone(repositoryURL, repositoryName, token, branch string, cloneOptions CloneOptions) (destination, gitCloneRoot string, err error) {
+	if len(cloneOptions.SparsePaths) > 0 {
+		return "", "", errors.New("Git error: sparse clones require the shell git client")
+	}
+
+	remoteURL, err := g.authenticate(repositoryURL, token, cloneOptions)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	log.Info().
+		Str("repository", repositoryURL).
+		Msg("Cloning repository.")
+
+	destination, err = ioutil.TempDir("/tmp", "tmp")
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "Git error")
+	}
+
+	gitCloneRoot = destination + "/" + repositoryName
+	options := &git.CloneOptions{
+		URL:  remoteURL,
+		Auth: g.auth,
+	}
+
+	if branch != "" {
+		options.ReferenceName = plumbing.NewBranchReferenceName(branch)
+	}
+
+	if cloneOptions.Shallow {
+		options.Depth = 1
+		options.SingleBranch = true
+	}
+
+	if _, err = git.PlainClone(gitCloneRoot, false, options); err != nil {
+		return destination, "", errors.Wrap(err, "Git error")
+	}
+
+	log.Info().
+		Msg("Successfully cloned to " + destination)
+
+	return destination, gitCloneRoot, nil
+}
+
+// Configure is a no-op, commits and tags are created with the fuse signature
+func (g *NativeGit) Configure(repositoryDir string) error {
+	return nil
+}
+
+// CurrentBranch returns the branch checked out in the repository directory
+func (g *NativeGit) CurrentBranch(repositoryDir string) (string, error) {
+	head, err := g.head(repositoryDir)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !head.Name().IsBranch() {
+		return "", errors.New("Git error: HEAD is not a branch")
+	}
+
+	return head.Name().Short(), nil
+}
+
+// CreateBranch creates and checks out a new branch with the given name
+func (g *NativeGit) CreateBranch(repositoryDir, branchName string) error {
+	log.Info().
+		Str("branchName", branchName).
+		Msg("Creating git branch.")
+
+	worktree, err := g.worktree(repositoryDir)
+
+	if err != nil {
+		return err
+	}
+
+	err = worktree.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branchName),
+		Create: true,
+		Keep:   true,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	log.Info().Msg("Successfully created branch " + branchName)
+
+	return nil
+}
+
+// Commit stages every change, including deletions, and commits them
+func (g *NativeGit) Commit(repositoryDir string) error {
+	log.Info().
+		Msg("Committing git changes.")
+
+	worktree, err := g.worktree(repositoryDir)
+
+	if err != nil {
+		return err
+	}
+
+	status, err := worktree.Status()
+
+	if err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	for path, fileStatus := range status {
+		switch fileStatus.Worktree {
+		case git.Unmodified:
+			continue
+		case git.Deleted:
+			_, err = worktree.Remove(path)
+		default:
+			_, err = worktree.Add(path)
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "Git error")
+		}
+	}
+
+	if _, err = worktree.Commit("Fuse automation", &git.CommitOptions{Author: signature()}); err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	log.Info().Msg("Successfully committed changes.")
+
+	return nil
+}
+
+// HeadSHA returns the commit sha of the repository directory HEAD
+func (g *NativeGit) HeadSHA(repositoryDir string) (string, error) {
+	head, err := g.head(repositoryDir)
+
+	if err != nil {
+		return "", err
+	}
+
+	return head.Hash().String(), nil
+}
+
+// ReadBlob returns the content of the blob sha of the repository directory
+func (g *NativeGit) ReadBlob(repositoryDir, sha string) ([]byte, error) {
+	repository, err := git.PlainOpen(repositoryDir)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	blob, err := repository.BlobObject(plumbing.NewHash(sha))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	reader, err := blob.Reader()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+
+	return content, errors.Wrap(err, "Git error")
+}
+
+// Tag creates an annotated tag on HEAD, pushed along with the branch
+func (g *NativeGit) Tag(repositoryDir, tag string) error {
+	log.Info().
+		Str("tag", tag).
+		Msg("Tagging git commit")
+
+	repository, err := git.PlainOpen(repositoryDir)
+
+	if err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	head, err := repository.Head()
+
+	if err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	_, err = repository.CreateTag(tag, head.Hash(), &git.CreateTagOptions{
+		Tagger:  signature(),
+		Message: "Fuse release " + tag,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	g.tags = append(g.tags, tag)
+
+	log.Info().Msg("Successfully tagged.")
+
+	return nil
+}
+
+// Push pushes the branch, and the tags created by this client, to origin
+func (g *NativeGit) Push(repositoryDir, branch string) error {
+	log.Info().
+		Msg("Pushing git changes.")
+
+	repository, err := git.PlainOpen(repositoryDir)
+
+	if err != nil {
+		return errors.Wrap(err, "Git error")
+	}
+
+	refSpecs := []config.RefSpec{config.RefSpec("refs/heads/" + branch + ":refs/heads/" + branch)}
+
+	for _, tag := range g.tags {
+		refSpecs = append(refSpecs, config.RefSpec("refs/tags/"+tag+":refs/tags/"+tag))
+	}
+
+	err = repository.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   refSpecs,
+		Auth:       g.auth,
+	})
+
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return errors.Wrap(err, "Git error")
+	}
+
+	log.Info().Msg("Successfully pushed changes.")
+
+	return nil
+}
+
+// authenticate sets the credentials of the remote, returning its url without the http user
+func (g *NativeGit) authenticate(repositoryURL, token string, cloneOptions CloneOptions) (string, error) {
+	if IsSSHURL(repositoryURL) {
+		if cloneOptions.SSHKey == "" {
+			return repositoryURL, nil
+		}
+
+		endpoint, err := transport.NewEndpoint(repositoryURL)
+
+		if err != nil {
+			return "", errors.Wrap(err, "Git error")
+		}
+
+		username := endpoint.User
+
+		if username == "" {
+			username = "git"
+		}
+
+		auth, err := gitssh.NewPublicKeysFromFile(username, cloneOptions.SSHKey, "")
+
+		if err != nil {
+			return "", errors.Wrap(err, "Git error")
+		}
+
+		if cloneOptions.KnownHosts != "" {
+			if auth.HostKeyCallback, err = gitssh.NewKnownHostsCallback(cloneOptions.KnownHosts); err != nil {
+				return "", errors.Wrap(err, "Git error")
+			}
+		}
+
+		g.auth = auth
+
+		return repositoryURL, nil
+	}
+
+	cloneURL, err := url.Parse(repositoryURL)
+
+	if err != nil {
+		return "", errors.Wrap(err, "Git error")
+	}
+
+	if token != "" && (cloneURL.Scheme == "https" || cloneURL.Scheme == "http") {
+		username := "automated"
+
+		if cloneURL.User != nil && cloneURL.User.Username() != "" {
+			username = cloneURL.User.Username()
+		}
+
+		cloneURL.User = nil
+		g.auth = &http.BasicAuth{Username: username, Password: token}
+	}
+
+	return cloneURL.String(), nil
+}
+
+func (g *NativeGit) head(repositoryDir string) (*plumbing.Reference, error) {
+	repository, err := git.PlainOpen(repositoryDir)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	head, err := repository.Head()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	return head, nil
+}
+
+func (g *NativeGit) worktree(repositoryDir string) (*git.Worktree, error) {
+	repository, err := git.PlainOpen(repositoryDir)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	worktree, err := repository.Worktree()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Git error")
+	}
+
+	return worktree, nil
+}
@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+func TestNativeGitAuthenticate(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "fuse-keys")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	key, knownHosts := filepath.Join(dir, "deploy"), filepath.Join(dir, "known_hosts")
+
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v: %s", err, out)
+	}
+
+	publicKey, err := ioutil.ReadFile(key + ".pub")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(knownHosts, append([]byte("github.com "), publicKey...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		token    string
+		options  CloneOptions
+		expected string
+		user     string
+	}{
+		{"https token", "https://github.com/org/repository", "token", CloneOptions{}, "https://github.com/org/repository", "automated"},
+		{"https user", "https://x-token-auth@bitbucket.org/org/repository.git", "token", CloneOptions{},
+			"https://bitbucket.org/org/repository.git", "x-token-auth"},
+		{"scp-like key", "git@github.com:org/repository.git", "token", CloneOptions{SSHKey: key},
+			"git@github.com:org/repository.git", "git"},
+		{"ssh key and known hosts", "ssh://deploy@github.com/org/repository.git", "", CloneOptions{SSHKey: key, KnownHosts: knownHosts},
+			"ssh://deploy@github.com/org/repository.git", "deploy"},
+		{"ssh without key", "git@github.com:org/repository.git", "token", CloneOptions{}, "git@github.com:org/repository.git", ""},
+		{"file", "file:///srv/git/repository.git", "token", CloneOptions{}, "file:///srv/git/repository.git", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &NativeGit{}
+			got, err := client.authenticate(test.url, test.token, test.options)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.expected {
+				t.Errorf("expected url %q, got %q", test.expected, got)
+			}
+
+			user := ""
+
+			switch auth := client.auth.(type) {
+			case *http.BasicAuth:
+				user = auth.Username
+			case *gitssh.PublicKeys:
+				user = auth.User
+			}
+
+			if user != test.user {
+				t.Errorf("expected user %q, got %q", test.user, user)
+			}
+		})
+	}
+
+	if _, err = (&NativeGit{}).authenticate("git@github.com:org/repository.git", "", CloneOptions{SSHKey: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected a missing key to fail")
+	}
+}
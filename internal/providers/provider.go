@@ -8,23 +8,25 @@ import (
 // Provider defines the necessary
 type Provider interface {
 	GetRepository() (*ProviderRepository, error)
-	CreatePullRequest(sourceBranch *string) (*ProviderPullRequest, error)
+	CreatePullRequest(sourceBranch, targetBranch *string) (*ProviderPullRequest, error)
 	GetCommonInput() *domain.CommonInput
 	GetPullRequestInput() *domain.PullRequestInput
 }
 
-// ProviderRepository encapsulates data about a provider repository
+// ProviderRepository encapsulates data about a provider repository.
+// CloneURL is only set when the repository can't be cloned through its WebURL.
+// SSHURL is the url cloning the repository over ssh, empty when the provider doesn't tell it.
+// DefaultBranch is empty when the provider can't tell it before cloning.
 type ProviderRepository struct {
-	WebURL string
-	Name   string
+	WebURL        string
+	CloneURL      string
+	SSHURL        string
+	Name          string
+	DefaultBranch string
 }
 
 // ProviderPullRequest encapsulates data about a provider pull request
 type ProviderPullRequest struct {
-	PullRequestID  string
-	PullRequestURL string
+	PullRequestID  string `json:"id"`
+	PullRequestURL string `json:"url,omitempty"`
 }
-
-// TargetBranch defines the default target branch used when executing fuse.
-// For git operations and pull request target
-const TargetBranch = "master"
@@ -0,0 +1,87 @@
+// Package providers exposes third party communication channels
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// restClient is a minimal json http client used by providers that do not have an official go sdk
+type restClient struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newRestClient(baseURL string, headers map[string]string) *restClient {
+	return &restClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  http.DefaultClient,
+	}
+}
+
+// do performs the request against baseURL + path. If body is not nil it will be sent json encoded and if out is not nil
+// the response body will be json decoded into it. Any non 2xx status code is treated as an error.
+func (c *restClient) do(method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+
+	if body != nil {
+		encoded, err := json.Marshal(body)
+
+		if err != nil {
+			return errors.Wrap(err, "Rest error")
+		}
+
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+
+	if err != nil {
+		return errors.Wrap(err, "Rest error")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := c.client.Do(req)
+
+	if err != nil {
+		return errors.Wrap(err, "Rest error")
+	}
+
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+
+	if err != nil {
+		return errors.Wrap(err, "Rest error")
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("Rest error: %s %s returned %d: %s", method, req.URL.Path, res.StatusCode,
+			strings.TrimSpace(string(resBody)))
+	}
+
+	if out != nil && len(resBody) > 0 {
+		if err = json.Unmarshal(resBody, out); err != nil {
+			return errors.Wrap(err, "Rest error")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package workflow contains the entry point to start the fuse process for the available provider implementation
+package workflow
+
+import (
+	"sync"
+
+	"fuse/internal/providers"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// FuseAll runs the fuse workflow for every provided repository, fusing at most parallelism repositories at a time.
+// Results are returned in the same order as the providers. An error is returned if fusing any of the repositories failed.
+func FuseAll(targets []providers.Provider, parallelism int) ([]*Result, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]*Result, len(targets))
+	slots := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(i int, target providers.Provider) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			// errors are kept in the result, one repository failing must not stop the others
+			results[i], _ = Fuse(target)
+		}(i, target)
+	}
+
+	wg.Wait()
+
+	failed := logSummary(results)
+
+	if failed > 0 {
+		return results, errors.Errorf("fuse failed for %d out of %d repositories", failed, len(results))
+	}
+
+	return results, nil
+}
+
+func logSummary(results []*Result) (failed int) {
+	for _, result := range results {
+		event := log.Info()
+
+		if result.Status == StatusFailed {
+			failed++
+			event = log.Error().Err(result.Err)
+		}
+
+		if result.PullRequest != nil {
+			event = event.
+				Str("pullRequestID", result.PullRequest.PullRequestID).
+				Str("pullRequestURL", result.PullRequest.PullRequestURL)
+		}
+
+		event.
+			Str("repository", result.Repository).
+			Str("status", string(result.Status)).
+			Str("branch", result.Branch).
+			Uint32("totalDiffs", result.WithDiffs).
+			Uint32("totalErrors", result.Errors).
+			Msg("Repository summary")
+	}
+
+	log.Info().
+		Int("repositories", len(results)).
+		Int("failed", failed).
+		Msg("Fuse summary")
+
+	return failed
+}
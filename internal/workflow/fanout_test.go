@@ -0,0 +1,52 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fuse/internal/domain"
+	"fuse/internal/providers"
+)
+
+func TestFuseAll(t *testing.T) {
+	first := newBareRemote(t, master, map[string]string{"config.yaml": "a: 1\n"})
+	defer os.RemoveAll(filepath.Dir(first))
+
+	second := newBareRemote(t, master, map[string]string{"config.yaml": "# managed by fuse\na: 2\n"})
+	defer os.RemoveAll(filepath.Dir(second))
+
+	content := newContentDir(t, map[string]string{"config.yaml": "a: 2\n"})
+	defer os.RemoveAll(content)
+
+	target := func(name, url string) providers.Provider {
+		return &providers.GitRemote{
+			URL: url,
+			Common: domain.CommonInput{
+				RepositoryName:   name,
+				ContentDir:       content,
+				Concurrency:      2,
+				Tag:              "v1",
+				CommentDelimiter: "#",
+			},
+		}
+	}
+
+	results, err := FuseAll([]providers.Provider{
+		target("first", "file://"+first),
+		target("second", "file://"+second),
+		target("missing", "file://"+filepath.Join(filepath.Dir(first), "missing.git")),
+	}, 2)
+
+	if err == nil {
+		t.Error("expected an error since one of the repositories doesn't exist")
+	}
+
+	expected := []Status{StatusPushed, StatusUnchanged, StatusFailed}
+
+	for i, result := range results {
+		if result.Status != expected[i] {
+			t.Errorf("expected %s to be %s, got %s", result.Repository, expected[i], result.Status)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package workflow contains the entry point to start the fuse process for the available provider implementation
+package workflow
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"fuse/internal/core"
+
+	"github.com/pkg/errors"
+)
+
+// FileStatus is the outcome of a single file
+type FileStatus string
+
+const (
+	// FileCreated means the file didn't exist in the target repository
+	FileCreated FileStatus = "created"
+	// FilePatched means the file existed and was changed
+	FilePatched FileStatus = "patched"
+	// FileUnchanged means the file already had the intended content
+	FileUnchanged FileStatus = "unchanged"
+	// FileErrored means the file could not be processed
+	FileErrored FileStatus = "errored"
+	// FileDeleted means the file existed and was deleted
+	FileDeleted FileStatus = "deleted"
+	// FileRenamed means the file existed and was moved
+	FileRenamed FileStatus = "renamed"
+	// FileConflicted means the update could not be merged with the changes made to the file
+	FileConflicted FileStatus = "conflicted"
+)
+
+// FileResult summarizes what happened to a single file of the content directory
+type FileResult struct {
+	Path   string     `json:"path"`
+	Status FileStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// Report is the machine readable summary of a fuse run
+type Report struct {
+	Repositories []*Result `json:"repositories"`
+	Failed       int       `json:"failed"`
+}
+
+// MarshalJSON serializes the result, exposing its error message
+func (r *Result) MarshalJSON() ([]byte, error) {
+	type result Result
+
+	message := ""
+
+	if r.Err != nil {
+		message = r.Err.Error()
+	}
+
+	return json.Marshal(&struct {
+		*result
+		Error string `json:"error,omitempty"`
+	}{
+		result: (*result)(r),
+		Error:  message,
+	})
+}
+
+// WriteReport writes the json report of the provided results to path. If path is - the report is written to stdout.
+func WriteReport(results []*Result, path string) error {
+	report := Report{Repositories: results}
+
+	for _, result := range results {
+		if result.Status == StatusFailed {
+			report.Failed++
+		}
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+
+	if err != nil {
+		return errors.Wrap(err, "Report error")
+	}
+
+	content = append(content, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(content)
+	} else {
+		err = ioutil.WriteFile(path, content, 0644)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "Report error")
+	}
+
+	return nil
+}
+
+func fileResults(results []core.WorkItemResult) []FileResult {
+	files := make([]FileResult, 0, len(results))
+
+	for _, res := range results {
+		file := FileResult{
+			Path:   res.CommonPath,
+			Status: FileUnchanged,
+		}
+
+		switch {
+		case res.Conflict:
+			file.Status = FileConflicted
+			file.Error = res.Err.Error()
+		case res.Err != nil:
+			file.Status = FileErrored
+			file.Error = res.Err.Error()
+		case res.Deleted && res.HasDiffs:
+			file.Status = FileDeleted
+		case res.Renamed && res.HasDiffs:
+			file.Status = FileRenamed
+		case res.Created:
+			file.Status = FileCreated
+		case res.HasDiffs:
+			file.Status = FilePatched
+		}
+
+		files = append(files, file)
+	}
+
+	return files
+}
@@ -0,0 +1,40 @@
+package workflow
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"fuse/internal/core"
+	"fuse/internal/providers"
+)
+
+func TestResultJSON(t *testing.T) {
+	result := &Result{
+		Repository:  "repo",
+		Status:      StatusFailed,
+		PullRequest: &providers.ProviderPullRequest{PullRequestID: "1", PullRequestURL: "http://pr"},
+		Files: fileResults([]core.WorkItemResult{
+			{CommonPath: "/a", Created: true, HasDiffs: true},
+			{CommonPath: "/b", HasDiffs: true},
+			{CommonPath: "/c"},
+			{CommonPath: "/d", Err: errors.New("boom")},
+		}),
+		Err: errors.New("failed"),
+	}
+
+	content, err := json.Marshal(result)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"repository":"repo","status":"failed","totalDiffs":0,"totalErrors":0,"baseBranch":"","branch":"",` +
+		`"pullRequest":{"id":"1","url":"http://pr"},"files":[{"path":"/a","status":"created"},{"path":"/b","status":"patched"},` +
+		`{"path":"/c","status":"unchanged"},{"path":"/d","status":"errored","error":"boom"}],"error":"failed"}`
+
+	if string(content) != expected {
+		t.Errorf("unexpected json:\n%s", content)
+	}
+}
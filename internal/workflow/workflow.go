@@ -2,72 +2,168 @@
 package workflow
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
 	"fuse/internal/core"
+	"fuse/internal/domain"
 	"fuse/internal/providers"
-	"os"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
-// Fuse kicks off the patching workflow
-func Fuse(provider providers.Provider) error {
+// Output is where dry runs print their diffs, stdout unless something else, e.g: the report, is written there
+var Output io.Writer = os.Stdout
+
+// Status is the outcome of fusing a single repository
+type Status string
+
+const (
+	// StatusPushed means changes were committed and pushed
+	StatusPushed Status = "pushed"
+	// StatusUnchanged means there was nothing to commit
+	StatusUnchanged Status = "unchanged"
+	// StatusFailed means the workflow stopped due to an error
+	StatusFailed Status = "failed"
+	// StatusDryRun means there were changes but, being a dry run, they were only reported
+	StatusDryRun Status = "dryRun"
+)
+
+// Result summarizes the fuse workflow on a single repository
+type Result struct {
+	Repository  string                         `json:"repository"`
+	Status      Status                         `json:"status"`
+	WithDiffs   uint32                         `json:"totalDiffs"`
+	Errors      uint32                         `json:"totalErrors"`
+	BaseBranch  string                         `json:"baseBranch"`
+	Branch      string                         `json:"branch"`
+	Tag         string                         `json:"tag,omitempty"`
+	CommitSHA   string                         `json:"commitSha,omitempty"`
+	PullRequest *providers.ProviderPullRequest `json:"pullRequest,omitempty"`
+	Files       []FileResult                   `json:"files"`
+	Err         error                          `json:"-"`
+}
+
+func (r *Result) fail(err error) (*Result, error) {
+	r.Status = StatusFailed
+	r.Err = logErrAndReturn(err)
+
+	return r, err
+}
+
+// Fuse kicks off the patching workflow. The returned result is never nil, even when an error is returned.
+func Fuse(provider providers.Provider) (*Result, error) {
 	log.Info().Msg("Fusing")
-	branchName := providers.TargetBranch
 
-	if provider.GetPullRequestInput().Enabled {
-		branchName = uuid.Must(uuid.NewRandom()).String()
+	result := &Result{
+		Repository: provider.GetCommonInput().RepositoryName,
+		Status:     StatusUnchanged,
 	}
 
-	gitCloneRoot, err := layoutStage(provider, branchName)
+	opts, err := crawlOptions(provider.GetCommonInput())
 
 	if err != nil {
-		return logErrAndReturn(err)
+		return result.fail(err)
 	}
 
+	git, err := providers.NewGitClient(provider)
+
+	if err != nil {
+		return result.fail(err)
+	}
+
+	cloneDir, gitCloneRoot, err := layoutStage(provider, git, opts, result)
+
 	// I'm ok if this errors and the folder is not removed. If this ends up not ok, return this error
-	defer os.RemoveAll(*gitCloneRoot)
+	if cloneDir != "" {
+		defer os.RemoveAll(cloneDir)
+	}
+
+	if err != nil {
+		return result.fail(err)
+	}
+
+	// permission bits of targets that don't expose them can't be compared
+	if api, ok := git.(*providers.APIGit); ok && !api.ModesKnown() {
+		opts.IgnoreModes = true
+	}
+
+	// bases of the files changed since fuse applied them are read from the history
+	opts.ReadBlob = func(sha string) ([]byte, error) {
+		return git.ReadBlob(gitCloneRoot, sha)
+	}
 
 	// start the crawling and diffing process
-	diffsChannel, err := core.Crawl(provider.GetCommonInput().ContentDir, *gitCloneRoot,
-		provider.GetCommonInput().CommentDelimiter, provider.GetCommonInput().Concurrency)
+	diffsChannel, err := core.Crawl(provider.GetCommonInput().ContentDir, gitCloneRoot, opts)
 
 	if err != nil {
-		return logErrAndReturn(err)
+		return result.fail(err)
 	}
 
 	diffs := <-diffsChannel
+	result.WithDiffs = diffs.WithDiffs
+	result.Errors = diffs.Error
+
+	sort.Slice(diffs.Results, func(i, j int) bool {
+		return diffs.Results[i].CommonPath < diffs.Results[j].CommonPath
+	})
+
+	result.Files = fileResults(diffs.Results)
+
+	if provider.GetCommonInput().DryRun {
+		return dryRun(result, diffs)
+	}
+
+	branchName := result.Branch
 
 	// only proceed with pushing changes we have any and we didn't find any error
 	if diffs.Error == 0 && diffs.WithDiffs > 0 {
-		err = providers.GitCommit(*gitCloneRoot)
+		err = git.Commit(gitCloneRoot)
 
 		if err != nil {
-			return logErrAndReturn(err)
+			return result.fail(err)
 		}
 
-		if providers.TargetBranch == branchName {
-			err = providers.GitTag(*gitCloneRoot, provider.GetCommonInput().Tag)
+		result.CommitSHA, err = git.HeadSHA(gitCloneRoot)
+
+		if err != nil {
+			return result.fail(err)
+		}
+
+		// only releases pushed straight to the base branch are tagged
+		if result.BaseBranch == branchName {
+			err = git.Tag(gitCloneRoot, provider.GetCommonInput().Tag)
 
 			if err != nil {
-				return logErrAndReturn(err)
+				return result.fail(err)
 			}
+
+			result.Tag = provider.GetCommonInput().Tag
 		}
 
-		err = providers.GitPush(*gitCloneRoot, branchName)
+		err = git.Push(gitCloneRoot, branchName)
 
 		if err != nil {
-			return logErrAndReturn(err)
+			return result.fail(err)
 		}
 
+		result.Status = StatusPushed
+
 		// create the associated pull request if fuse was configured to do so
 		if provider.GetPullRequestInput().Enabled {
-			pr, err := provider.CreatePullRequest(&branchName)
+			pr, err := provider.CreatePullRequest(&branchName, &result.BaseBranch)
 
 			if err != nil {
-				return logErrAndReturn(err)
+				return result.fail(err)
 			}
+
+			result.PullRequest = pr
+
 			log.Info().
 				Uint32("totalDiffs", diffs.WithDiffs).
 				Str("pullRequestID", pr.PullRequestID).
@@ -77,21 +173,95 @@ func Fuse(provider providers.Provider) error {
 
 		log.Info().
 			Uint32("total_diffs", diffs.WithDiffs).
-			Msg("Pushed changes to master")
+			Msg("Pushed changes to " + branchName)
 
-		return nil
+		return result, nil
 	} else if diffs.Error > 0 {
 		log.Error().
 			Uint32("total_errors", diffs.Error).
 			Msg("Cannot proceed due to errors.")
 
-		return errors.New("crawling process encountered errors")
+		result.Status = StatusFailed
+		result.Err = errors.New("crawling process encountered errors")
+
+		return result, result.Err
 	}
 
 	log.Info().
 		Msg("No changes to commit")
 
-	return nil
+	return result, nil
+}
+
+// dryRun prints the unified diff of every changed file instead of committing and pushing them
+func dryRun(result *Result, diffs *core.CrawlResult) (*Result, error) {
+	var output strings.Builder
+
+	for _, res := range diffs.Results {
+		if res.Err == nil && res.HasDiffs {
+			output.WriteString(res.Diff)
+		}
+	}
+
+	if output.Len() > 0 {
+		fmt.Fprint(Output, "# fuse dry run: "+result.Repository+"\n"+output.String())
+	}
+
+	log.Info().
+		Uint32("total_diffs", result.WithDiffs).
+		Uint32("total_errors", result.Errors).
+		Msg("Dry run, nothing was committed or pushed")
+
+	if result.Errors > 0 {
+		result.Status = StatusFailed
+		result.Err = errors.New("crawling process encountered errors")
+
+		return result, result.Err
+	}
+
+	if result.WithDiffs > 0 {
+		result.Status = StatusDryRun
+	}
+
+	return result, nil
+}
+
+// crawlOptions parses the cli inputs configuring how the content directory is applied
+func crawlOptions(input *domain.CommonInput) (core.CrawlOptions, error) {
+	listMerge, err := core.ParseListMerge(input.ListMerge)
+
+	if err != nil {
+		return core.CrawlOptions{}, err
+	}
+
+	rules, err := core.ParseStrategyRules(input.Strategies)
+
+	if err != nil {
+		return core.CrawlOptions{}, err
+	}
+
+	commentStyles, err := core.ParseCommentStyles(input.CommentStyles)
+
+	if err != nil {
+		return core.CrawlOptions{}, err
+	}
+
+	return core.CrawlOptions{
+		CommentDelimiter: input.CommentDelimiter,
+		CommentStyles:    commentStyles,
+		Concurrency:      input.Concurrency,
+		Rules:            rules,
+		StructuredMerge:  input.StructuredMerge,
+		ListMerge:        listMerge,
+		Template: core.TemplateData{
+			Repository: input.RepositoryName,
+			Owner:      input.Owner,
+			Vars:       input.Vars,
+		},
+		Include:  input.Include,
+		Exclude:  input.Exclude,
+		Symlinks: input.Symlinks,
+	}, nil
 }
 
 func logErrAndReturn(err error) error {
@@ -102,34 +272,98 @@ func logErrAndReturn(err error) error {
 	return err
 }
 
-func layoutStage(provider providers.Provider, branchName string) (*string, error) {
-	defaultWorkingDir := "/tmp"
+// layoutStage clones the repository on its base branch and checks out the branch fuse works on, setting both in the result.
+// The base branch is the one provided by the user or, by default, the repository default branch.
+func layoutStage(provider providers.Provider, git providers.GitClient, opts core.CrawlOptions, result *Result) (cloneDir, gitCloneRoot string, err error) {
 	gitRepo, err := provider.GetRepository()
 
 	if err != nil {
-		return nil, err
+		return "", "", err
+	}
+
+	cloneURL := gitRepo.WebURL
+
+	if gitRepo.CloneURL != "" {
+		cloneURL = gitRepo.CloneURL
+	}
+
+	// deploy keys clone and push over ssh, the token is only used for the provider api
+	if provider.GetCommonInput().SSHKey != "" {
+		if gitRepo.SSHURL == "" {
+			return "", "", errors.Errorf("no ssh url known for repository %s", gitRepo.Name)
+		}
+
+		cloneURL = gitRepo.SSHURL
+	}
+
+	baseBranch := provider.GetCommonInput().BaseBranch
+
+	if baseBranch == "" {
+		baseBranch = gitRepo.DefaultBranch
+	}
+
+	cloneOptions, err := cloneOptions(provider.GetCommonInput(), opts)
+
+	if err != nil {
+		return "", "", err
 	}
 
-	err = providers.ConfigureGit(&defaultWorkingDir)
+	cloneDir, gitCloneRoot, err = git.Clone(cloneURL, gitRepo.Name, provider.GetCommonInput().Pat, baseBranch, cloneOptions)
 
 	if err != nil {
-		return nil, err
+		return cloneDir, "", err
 	}
 
-	_, gitCloneRoot, err := providers.GitClone(gitRepo.WebURL, gitRepo.Name, provider.GetCommonInput().Pat)
+	// providers that can't tell the default branch before cloning rely on the branch checked out by the clone
+	if baseBranch == "" {
+		baseBranch, err = git.CurrentBranch(gitCloneRoot)
+
+		if err != nil {
+			return cloneDir, "", err
+		}
+	}
+
+	result.BaseBranch = baseBranch
+	result.Branch = baseBranch
+
+	err = git.Configure(gitCloneRoot)
 
 	if err != nil {
-		return nil, err
+		return cloneDir, "", err
 	}
 
 	// Only create the branch if we're told to use a branch
-	if provider.GetPullRequestInput().Enabled && branchName != "" {
-		err = providers.CreateGitBranch(gitCloneRoot, branchName)
+	if provider.GetPullRequestInput().Enabled {
+		result.Branch = uuid.Must(uuid.NewRandom()).String()
+
+		err = git.CreateBranch(gitCloneRoot, result.Branch)
 
 		if err != nil {
-			return nil, err
+			return cloneDir, "", err
 		}
 	}
 
-	return &gitCloneRoot, nil
+	return cloneDir, gitCloneRoot, nil
+}
+
+// cloneOptions returns the options limiting the clone. Sparse clones check out the paths the content directory applies to,
+// along with the state fuse keeps for them, and so does the api git client. A content directory without files is cloned as a whole.
+func cloneOptions(input *domain.CommonInput, opts core.CrawlOptions) (providers.CloneOptions, error) {
+	options := providers.CloneOptions{Shallow: input.Shallow, SSHKey: input.SSHKey, KnownHosts: input.KnownHosts}
+
+	if !input.Sparse && input.GitClient != providers.GitClientAPI {
+		return options, nil
+	}
+
+	paths, err := core.ContentPaths(input.ContentDir, opts)
+
+	if err != nil {
+		return options, err
+	}
+
+	if len(paths) > 0 {
+		options.SparsePaths = append(paths, core.StateFile)
+	}
+
+	return options, nil
 }
@@ -1 +1,750 @@
 package workflow
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"fuse/internal/core"
+	"fuse/internal/domain"
+	"fuse/internal/providers"
+
+	"github.com/pkg/errors"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+
+	return string(out)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const master = "master"
+
+// newBareRemote creates a bare repository with a single commit on its default branch containing the given files
+func newBareRemote(t *testing.T, defaultBranch string, files map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	root, err := ioutil.TempDir("", "fuse-remote")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bare := filepath.Join(root, "remote.git")
+	seed := filepath.Join(root, "seed")
+
+	git(t, root, "init", "--bare", bare)
+	git(t, bare, "symbolic-ref", "HEAD", "refs/heads/"+defaultBranch)
+	git(t, root, "init", seed)
+	git(t, seed, "checkout", "-b", defaultBranch)
+
+	for path, content := range files {
+		writeFile(t, filepath.Join(seed, path), content)
+	}
+
+	git(t, seed, "add", "-A")
+	git(t, seed, "-c", "user.name=seed", "-c", "user.email=seed@fuse.io", "commit", "-m", "seed")
+	git(t, seed, "push", bare, defaultBranch)
+
+	return bare
+}
+
+func newContentDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "fuse-content")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, content := range files {
+		writeFile(t, filepath.Join(dir, path), content)
+	}
+
+	return dir
+}
+
+func TestFuseGitRemote(t *testing.T) {
+	for _, client := range []string{providers.GitClientNative, providers.GitClientShell} {
+		t.Run(client, func(t *testing.T) {
+			bare := newBareRemote(t, master, map[string]string{"config.yaml": "a: 1\n"})
+			defer os.RemoveAll(filepath.Dir(bare))
+
+			content := newContentDir(t, map[string]string{"config.yaml": "a: 2\n", "nested/new.txt": "hello\n"})
+			defer os.RemoveAll(content)
+
+			result, err := Fuse(&providers.GitRemote{
+				URL: "file://" + bare,
+				Common: domain.CommonInput{
+					RepositoryName:   "remote",
+					ContentDir:       content,
+					Concurrency:      2,
+					Tag:              "v1",
+					CommentDelimiter: "#",
+					GitClient:        client,
+				},
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sha := strings.TrimSpace(git(t, bare, "rev-parse", master)); result.CommitSHA != sha || result.Tag != "v1" {
+				t.Errorf("expected commit %s tagged v1, got %+v", sha, result)
+			}
+
+			if len(result.Files) != 2 || result.Files[0].Status != FilePatched || result.Files[1].Status != FileCreated {
+				t.Errorf("unexpected file results %+v", result.Files)
+			}
+
+			if got := git(t, bare, "show", master+":config.yaml"); got != "# managed by fuse\na: 2\n" {
+				t.Errorf("unexpected patched content %q", got)
+			}
+
+			if got := git(t, bare, "show", master+":nested/new.txt"); got != "# managed by fuse\nhello\n" {
+				t.Errorf("unexpected created content %q", got)
+			}
+
+			if got := git(t, bare, "tag", "--list"); strings.TrimSpace(got) != "v1" {
+				t.Errorf("expected tag v1, got %q", got)
+			}
+
+			if got := git(t, bare, "cat-file", "-t", "v1"); got != "tag\n" {
+				t.Errorf("expected an annotated tag, got %q", got)
+			}
+		})
+	}
+}
+
+func TestFuseGitRemoteWithBranch(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"config.yaml": "a: 1\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{"config.yaml": "a: 2\n"})
+	defer os.RemoveAll(content)
+
+	_, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+		},
+		PullRequest: domain.PullRequestInput{Enabled: true},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := git(t, bare, "show", master+":config.yaml"); got != "a: 1\n" {
+		t.Errorf("expected %s to be untouched, got %q", master, got)
+	}
+
+	branches := strings.Fields(git(t, bare, "for-each-ref", "--format=%(refname:short)", "refs/heads"))
+
+	if len(branches) != 2 {
+		t.Errorf("expected a fuse branch to be pushed, got %v", branches)
+	}
+}
+
+func TestFuseSSHKeyWithoutSSHURL(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"config.yaml": "a: 1\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{"config.yaml": "a: 2\n"})
+	defer os.RemoveAll(content)
+
+	result, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+			SSHKey:           "/keys/deploy",
+		},
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "no ssh url") {
+		t.Fatalf("expected the missing ssh url to fail the run, got %v %+v", err, result)
+	}
+}
+
+func TestFuseDryRun(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"config.yaml": "a: 1\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{"config.yaml": "a: 2\n"})
+	defer os.RemoveAll(content)
+
+	result, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+			DryRun:           true,
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != StatusDryRun || result.WithDiffs != 1 {
+		t.Errorf("unexpected dry run result %+v", result)
+	}
+
+	if got := git(t, bare, "show", master+":config.yaml"); got != "a: 1\n" {
+		t.Errorf("expected %s to be untouched, got %q", master, got)
+	}
+
+	if got := git(t, bare, "tag", "--list"); got != "" {
+		t.Errorf("expected no tags, got %q", got)
+	}
+}
+
+func TestFuseDefaultBranch(t *testing.T) {
+	bare := newBareRemote(t, "main", map[string]string{"config.yaml": "a: 1\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	git(t, bare, "branch", "develop", "main")
+
+	content := newContentDir(t, map[string]string{"config.yaml": "a: 2\n"})
+	defer os.RemoveAll(content)
+
+	target := &providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+		},
+	}
+
+	result, err := Fuse(target)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.BaseBranch != "main" || result.Branch != "main" {
+		t.Errorf("expected the remote default branch to be used, got %+v", result)
+	}
+
+	if got := git(t, bare, "show", "main:config.yaml"); got != "# managed by fuse\na: 2\n" {
+		t.Errorf("unexpected patched content %q", got)
+	}
+
+	target.Common.BaseBranch = "develop"
+	target.Common.Tag = "v2"
+
+	if _, err = Fuse(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := git(t, bare, "show", "develop:config.yaml"); got != "# managed by fuse\na: 2\n" {
+		t.Errorf("unexpected patched content %q", got)
+	}
+}
+
+func TestFuseMergesLocalChanges(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"config.yaml": "a: 1\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{"config.yaml": "a: 2\nb: 1\nc: 1\n"})
+	defer os.RemoveAll(content)
+
+	provider := &providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+		},
+	}
+
+	if _, err := Fuse(provider); err != nil {
+		t.Fatal(err)
+	}
+
+	// the state references the applied content, read back from the history once the file changes
+	if got := git(t, bare, "show", master+":"+core.StateFile); !strings.Contains(got, core.BlobSHA("# managed by fuse\na: 2\nb: 1\nc: 1\n")) {
+		t.Errorf("unexpected recorded state %q", got)
+	}
+
+	// a change made on the target repository after fuse applied its content
+	seed := filepath.Join(filepath.Dir(bare), "seed")
+	git(t, seed, "pull", bare, master)
+	writeFile(t, filepath.Join(seed, "config.yaml"), "# managed by fuse\na: 2\nb: 1\nc: local\n")
+	git(t, seed, "-c", "user.name=seed", "-c", "user.email=seed@fuse.io", "commit", "-am", "local change")
+	git(t, seed, "push", bare, master)
+
+	writeFile(t, filepath.Join(content, "config.yaml"), "a: 3\nb: 1\nc: 1\n")
+	provider.Common.Tag = "v2"
+
+	if _, err := Fuse(provider); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := git(t, bare, "show", master+":config.yaml"); got != "# managed by fuse\na: 3\nb: 1\nc: local\n" {
+		t.Errorf("expected the local change to be kept, got %q", got)
+	}
+
+	// updating the locally changed line conflicts
+	writeFile(t, filepath.Join(content, "config.yaml"), "a: 3\nb: 1\nc: 2\n")
+	provider.Common.Tag = "v3"
+
+	result, err := Fuse(provider)
+
+	if err == nil || len(result.Files) != 1 || result.Files[0].Status != FileConflicted {
+		t.Errorf("expected a conflict, got %v %+v", err, result.Files)
+	}
+}
+
+func TestFuseStructuredMerge(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"values.yaml": "image:\n  tag: v1\nreplicas: 3\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{"values.yaml": "image:\n  tag: v2\n"})
+	defer os.RemoveAll(content)
+
+	_, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+			StructuredMerge:  true,
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := git(t, bare, "show", master+":values.yaml"); got != "image:\n  tag: v2\nreplicas: 3\n" {
+		t.Errorf("unexpected merged content %q", got)
+	}
+}
+
+func TestFuseStrategies(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{".gitignore": "bin/\n", "legacy.txt": "old\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{".gitignore": "dist/\n", "legacy.txt": ""})
+	defer os.RemoveAll(content)
+
+	result, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+			Strategies:       []string{".gitignore=append", "legacy.txt=delete"},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Files) != 2 || result.Files[0].Status != FilePatched || result.Files[1].Status != FileDeleted {
+		t.Errorf("unexpected file results %+v", result.Files)
+	}
+
+	if got := git(t, bare, "ls-tree", "--name-only", master); got != ".gitignore\n" {
+		t.Errorf("unexpected files %q", got)
+	}
+
+	if got := git(t, bare, "show", master+":.gitignore"); got != "bin/\ndist/\n" {
+		t.Errorf("unexpected appended content %q", got)
+	}
+}
+
+func TestFuseTombstones(t *testing.T) {
+	for _, client := range []string{providers.GitClientNative, providers.GitClientShell} {
+		t.Run(client, func(t *testing.T) {
+			bare := newBareRemote(t, master, map[string]string{"legacy.txt": "old\n", "old.yaml": "a: 1\n", "keep.txt": "keep\n"})
+			defer os.RemoveAll(filepath.Dir(bare))
+
+			content := newContentDir(t, map[string]string{"legacy.txt.fuse-delete": "", "old.yaml.fuse-rename": "config/new.yaml\n"})
+			defer os.RemoveAll(content)
+
+			provider := &providers.GitRemote{
+				URL: "file://" + bare,
+				Common: domain.CommonInput{
+					RepositoryName:   "remote",
+					ContentDir:       content,
+					Concurrency:      2,
+					Tag:              "v1",
+					CommentDelimiter: "#",
+					GitClient:        client,
+				},
+			}
+
+			result, err := Fuse(provider)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Files) != 2 || result.Files[0].Status != FileDeleted || result.Files[1].Status != FileRenamed {
+				t.Errorf("unexpected file results %+v", result.Files)
+			}
+
+			if got := git(t, bare, "ls-tree", "-r", "--name-only", master); got != "config/new.yaml\nkeep.txt\n" {
+				t.Errorf("unexpected files %q", got)
+			}
+
+			// tombstones already applied don't change anything
+			provider.Common.Tag = "v2"
+			result, err = Fuse(provider)
+
+			if err != nil || result.Status != StatusUnchanged {
+				t.Errorf("expected no changes, got %v %+v", err, result)
+			}
+		})
+	}
+}
+
+func TestFuseTemplates(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"README.md": "readme\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	content := newContentDir(t, map[string]string{"deploy/values.yaml.fuse-tmpl": "name: {{ .Repository }}\nenv: {{ .Vars.environment }}\n"})
+	defer os.RemoveAll(content)
+
+	_, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+			Vars:             map[string]string{"environment": "staging"},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := git(t, bare, "show", master+":deploy/values.yaml"); got != "# managed by fuse\nname: remote\nenv: staging\n" {
+		t.Errorf("unexpected rendered content %q", got)
+	}
+}
+
+func TestFuseBinaryAndModes(t *testing.T) {
+	bare := newBareRemote(t, master, map[string]string{"README.md": "readme\n"})
+	defer os.RemoveAll(filepath.Dir(bare))
+
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x01"
+	content := newContentDir(t, map[string]string{"assets/logo.png": binary, "scripts/build.sh": "echo build\n",
+		"config.txt": "a=1\n"})
+	defer os.RemoveAll(content)
+
+	if err := os.Chmod(filepath.Join(content, "config.txt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chmod(filepath.Join(content, "scripts/build.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Fuse(&providers.GitRemote{
+		URL: "file://" + bare,
+		Common: domain.CommonInput{
+			RepositoryName:   "remote",
+			ContentDir:       content,
+			Concurrency:      2,
+			Tag:              "v1",
+			CommentDelimiter: "#",
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Files) != 3 {
+		t.Errorf("unexpected file results %+v", result.Files)
+	}
+
+	if got := git(t, bare, "show", master+":assets/logo.png"); got != binary {
+		t.Errorf("expected the binary file to be copied verbatim, got %q", got)
+	}
+
+	for path, mode := range map[string]string{"scripts/build.sh": "100755", "config.txt": "100644"} {
+		if got := git(t, bare, "ls-tree", master, path); !strings.HasPrefix(got, mode+" blob") {
+			t.Errorf("expected %s with mode %s, got %q", path, mode, got)
+		}
+	}
+}
+
+func TestFuseShallowAndSparse(t *testing.T) {
+	tests := []struct {
+		name      string
+		gitClient string
+		sparse    bool
+	}{
+		{"shallow native", providers.GitClientNative, false},
+		{"shallow shell", providers.GitClientShell, false},
+		{"shallow sparse", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bare := newBareRemote(t, master, map[string]string{"config/app.yaml": "a: 1\n", "old.yaml": "b: 1\n", "other/big.txt": "big\n"})
+			defer os.RemoveAll(filepath.Dir(bare))
+
+			content := newContentDir(t, map[string]string{"config/app.yaml": "a: 2\n", "old.yaml.fuse-rename": "moved/old.yaml\n"})
+			defer os.RemoveAll(content)
+
+			provider := &providers.GitRemote{
+				URL: "file://" + bare,
+				Common: domain.CommonInput{
+					RepositoryName:   "remote",
+					ContentDir:       content,
+					Concurrency:      2,
+					Tag:              "v1",
+					CommentDelimiter: "#",
+					GitClient:        test.gitClient,
+					Shallow:          true,
+					Sparse:           test.sparse,
+				},
+			}
+
+			if _, err := Fuse(provider); err != nil {
+				t.Fatal(err)
+			}
+
+			// the second run merges with the state recorded by the first one, on top of a shallow clone
+			writeFile(t, filepath.Join(content, "config/app.yaml"), "a: 3\n")
+			provider.Common.Tag = "v2"
+			result, err := Fuse(provider)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result.Files) != 2 || result.Files[0].Status != FilePatched || result.Files[1].Status != FileUnchanged {
+				t.Errorf("unexpected file results %+v", result.Files)
+			}
+
+			expected := ".fuse/state.json\nconfig/app.yaml\nmoved/old.yaml\nother/big.txt\n"
+
+			if got := git(t, bare, "ls-tree", "-r", "--name-only", master); got != expected {
+				t.Errorf("unexpected files %q", got)
+			}
+
+			if got := git(t, bare, "show", master+":config/app.yaml"); got != "# managed by fuse\na: 3\n" {
+				t.Errorf("unexpected patched content %q", got)
+			}
+		})
+	}
+}
+
+// apiRemote is an in memory providers.APIProvider applying the pushed changes to its files. Like azure devops, it drops
+// permission bits when unknownModes is set. Pushed contents are kept as blobs, read back by their git blob sha.
+type apiRemote struct {
+	providers.GitRemote
+	files        map[string]providers.RemoteFile
+	blobs        map[string][]byte
+	refs         map[string]string
+	unknownModes bool
+}
+
+func (a *apiRemote) BranchSHA(branch string) (string, error) {
+	return a.refs[branch], nil
+}
+
+func (a *apiRemote) ReadFiles(commitSHA string, paths []string) (map[string]providers.RemoteFile, error) {
+	files := map[string]providers.RemoteFile{}
+
+	for _, path := range paths {
+		if file, exists := a.files[path]; exists {
+			files[path] = file
+		}
+	}
+
+	return files, nil
+}
+
+func (a *apiRemote) PushFiles(branch, parentSHA string, newBranch bool, changes []providers.FileChange, message string) (string, error) {
+	for _, change := range changes {
+		if change.Deleted {
+			delete(a.files, change.Path)
+		} else {
+			a.files[change.Path] = providers.RemoteFile{Content: change.Content, Mode: change.Mode}
+			a.blobs[core.BlobSHA(string(change.Content))] = change.Content
+
+			if a.unknownModes {
+				a.files[change.Path] = providers.RemoteFile{Content: change.Content, Mode: 0644, ModeUnknown: true}
+			}
+		}
+	}
+
+	a.refs[branch] = "commit" + strconv.Itoa(len(a.refs))
+
+	return a.refs[branch], nil
+}
+
+func (a *apiRemote) ReadBlob(sha string) ([]byte, error) {
+	if content, exists := a.blobs[sha]; exists {
+		return content, nil
+	}
+
+	return nil, errors.Errorf("blob %s not found", sha)
+}
+
+func (a *apiRemote) CreateTag(tag, commitSHA, message string) error {
+	a.refs[tag] = commitSHA
+
+	return nil
+}
+
+func TestFuseAPI(t *testing.T) {
+	content := newContentDir(t, map[string]string{"config/app.yaml": "a: 2\n", "legacy.txt.fuse-delete": ""})
+	defer os.RemoveAll(content)
+
+	remote := &apiRemote{
+		GitRemote: providers.GitRemote{
+			Common: domain.CommonInput{
+				RepositoryName:   "remote",
+				BaseBranch:       master,
+				ContentDir:       content,
+				Concurrency:      2,
+				Tag:              "v1",
+				CommentDelimiter: "#",
+				GitClient:        providers.GitClientAPI,
+			},
+		},
+		files: map[string]providers.RemoteFile{
+			"config/app.yaml": {Content: []byte("a: 1\n"), Mode: 0644},
+			"legacy.txt":      {Content: []byte("old\n"), Mode: 0644},
+			"other.txt":       {Content: []byte("untouched\n"), Mode: 0644},
+		},
+		blobs: map[string][]byte{},
+		refs:  map[string]string{master: "base"},
+	}
+
+	result, err := Fuse(remote)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != StatusPushed || result.CommitSHA != remote.refs[master] || remote.refs["v1"] != result.CommitSHA {
+		t.Errorf("expected the commit to be pushed and tagged, got %+v %v", result, remote.refs)
+	}
+
+	if got := string(remote.files["config/app.yaml"].Content); got != "# managed by fuse\na: 2\n" {
+		t.Errorf("unexpected patched content %q", got)
+	}
+
+	if _, exists := remote.files["legacy.txt"]; exists {
+		t.Error("expected legacy.txt to be deleted")
+	}
+
+	if _, exists := remote.files[core.StateFile]; !exists || len(remote.files) != 3 {
+		t.Errorf("expected the fuse state to be pushed along the untouched files, got %v", remote.files)
+	}
+
+	// the second run merges with the base read from the remote history, the file being changed since
+	remote.files["config/app.yaml"] = providers.RemoteFile{Content: []byte("# local\n# managed by fuse\na: 2\n"), Mode: 0644}
+	writeFile(t, filepath.Join(content, "config/app.yaml"), "a: 3\n")
+	remote.Common.Tag = "v2"
+
+	if _, err = Fuse(remote); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(remote.files["config/app.yaml"].Content); got != "# local\n# managed by fuse\na: 3\n" {
+		t.Errorf("expected the remote change to be kept, got %q", got)
+	}
+}
+
+func TestFuseAPIUnknownModes(t *testing.T) {
+	// content files are executable, the remote can't tell
+	content := newContentDir(t, map[string]string{"run.sh": "echo\n"})
+	defer os.RemoveAll(content)
+
+	remote := &apiRemote{
+		GitRemote: providers.GitRemote{
+			Common: domain.CommonInput{
+				RepositoryName:   "remote",
+				BaseBranch:       master,
+				ContentDir:       content,
+				Concurrency:      2,
+				Tag:              "v1",
+				CommentDelimiter: "#",
+				GitClient:        providers.GitClientAPI,
+			},
+		},
+		files:        map[string]providers.RemoteFile{},
+		blobs:        map[string][]byte{},
+		refs:         map[string]string{master: "base"},
+		unknownModes: true,
+	}
+
+	if _, err := Fuse(remote); err != nil {
+		t.Fatal(err)
+	}
+
+	head := remote.refs[master]
+	remote.Common.Tag = "v2"
+	result, err := Fuse(remote)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Status != StatusUnchanged || remote.refs[master] != head {
+		t.Errorf("expected the unknown modes not to be changes, got %+v", result)
+	}
+}